@@ -0,0 +1,50 @@
+// Команда budgets пересчитывает кэш потраченных сумм бюджетов.
+//
+//	go run ./cmd/budgets recalculate [-user ID]
+package main
+
+import (
+	"context"
+	"finance/internal/db"
+	"finance/internal/models"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: budgets recalculate [-user ID]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "recalculate" {
+		usage()
+	}
+
+	flags := flag.NewFlagSet("recalculate", flag.ExitOnError)
+	user := flags.Uint("user", 0, "recalculate only this user")
+	flags.Parse(os.Args[2:])
+
+	err := db.InitDB(
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	var userID *uint
+	if *user != 0 {
+		userID = user
+	}
+	updated, err := models.RecalculateBudgets(context.Background(), userID)
+	if err != nil {
+		log.Fatal("Failed to recalculate budgets:", err)
+	}
+	log.Printf("Recalculated %d budgets", updated)
+}
@@ -15,13 +15,18 @@ import (
 	"finance/internal/handlers"
 	"finance/internal/db"
 	"finance/internal/middleware"
+	"finance/internal/models"
+	"context"
+	"time"
+	// База часовых поясов для пользователей, в образе alpine ее нет
+	_ "time/tzdata"
 >>>>>>> my-feature-branch
 )
 
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 <<<<<<< HEAD
@@ -58,13 +63,30 @@ func main() {
 <<<<<<< HEAD
 	taskHandler := handlers.NewTaskHandler()
 	notificationHandler := handlers.NewNotificationHandler()
+	recurringHandler := handlers.NewRecurringHandler()
 	categoryHandler := handlers.NewCategoryHandler()
 =======
+	// Агрегаты статистики по уже существующим транзакциям собираются один раз
+	if err := models.MigrateRollups(context.Background()); err != nil {
+		log.Fatal("Failed to build rollups:", err)
+	}
+
 	transactionHandler := handlers.NewTransactionHandler()
 	categoryHandler := handlers.NewCategoryHandler()
 	budgetHandler := handlers.NewBudgetHandler()
+	budgetTemplateHandler := handlers.NewBudgetTemplateHandler()
+	envelopeHandler := handlers.NewEnvelopeHandler()
+	notificationHandler := handlers.NewNotificationHandler()
+	recurringHandler := handlers.NewRecurringHandler()
 	statisticsHandler := handlers.NewStatisticsHandler()
 	exportHandler := handlers.NewExportHandler()
+	goalHandler := handlers.NewGoalHandler()
+	forecastHandler := handlers.NewForecastHandler()
+	anomalyHandler := handlers.NewAnomalyHandler()
+	subscriptionHandler := handlers.NewSubscriptionHandler()
+	netWorthHandler := handlers.NewNetWorthHandler()
+	metricsHandler := handlers.NewMetricsHandler()
+	settingsHandler := handlers.NewSettingsHandler()
 >>>>>>> my-feature-branch
 
 	r.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
@@ -109,14 +131,107 @@ func main() {
 
 	api.HandleFunc("/categories", categoryHandler.Create).Methods("POST", "OPTIONS")
 	api.HandleFunc("/categories", categoryHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/categories/preset", categoryHandler.ApplyPreset).Methods("POST", "OPTIONS")
+	api.HandleFunc("/categories/order", categoryHandler.Reorder).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/categories/{id}/parent", categoryHandler.Move).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/categories/{id}/merge", categoryHandler.Merge).Methods("POST", "OPTIONS")
+	api.HandleFunc("/categories/{id}", categoryHandler.Update).Methods("PUT", "PATCH", "OPTIONS")
+	api.HandleFunc("/categories/{id}", categoryHandler.Delete).Methods("DELETE", "OPTIONS")
 
 	api.HandleFunc("/budgets", budgetHandler.Create).Methods("POST", "OPTIONS")
 	api.HandleFunc("/budgets", budgetHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/budgets/recalculate", budgetHandler.Recalculate).Methods("POST", "OPTIONS")
+	api.HandleFunc("/budgets/history", budgetHandler.History).Methods("GET", "OPTIONS")
+	api.HandleFunc("/budgets/summary", budgetHandler.Summary).Methods("GET", "OPTIONS")
+	api.HandleFunc("/budgets/limit", budgetHandler.SetLimit).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/budgets/report", budgetHandler.Report).Methods("POST", "OPTIONS")
+	api.HandleFunc("/budgets/{id}", budgetHandler.Update).Methods("PUT", "PATCH", "OPTIONS")
 	api.HandleFunc("/budgets/{id}", budgetHandler.Delete).Methods("DELETE", "OPTIONS")
 
+	api.HandleFunc("/budget-templates", budgetTemplateHandler.Create).Methods("POST", "OPTIONS")
+	api.HandleFunc("/budget-templates", budgetTemplateHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/budget-templates/{id}", budgetTemplateHandler.Delete).Methods("DELETE", "OPTIONS")
+
+	api.HandleFunc("/envelopes", envelopeHandler.Summary).Methods("GET", "OPTIONS")
+	api.HandleFunc("/envelopes/mode", envelopeHandler.SetMode).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/envelopes/assign", envelopeHandler.Assign).Methods("POST", "OPTIONS")
+	api.HandleFunc("/envelopes/move", envelopeHandler.Move).Methods("POST", "OPTIONS")
+
+	api.HandleFunc("/notifications", notificationHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/notifications/{id}/read", notificationHandler.MarkAsRead).Methods("POST", "OPTIONS")
+	api.HandleFunc("/notifications/check", notificationHandler.CheckBudgets).Methods("POST", "OPTIONS")
+
+	api.HandleFunc("/recurring", recurringHandler.Create).Methods("POST", "OPTIONS")
+	api.HandleFunc("/recurring", recurringHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/recurring/{id}", recurringHandler.Delete).Methods("DELETE", "OPTIONS")
+
+	api.HandleFunc("/subscriptions", subscriptionHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/subscriptions/confirm", subscriptionHandler.Confirm).Methods("POST", "OPTIONS")
+	api.HandleFunc("/subscriptions/dismiss", subscriptionHandler.Dismiss).Methods("POST", "OPTIONS")
+
+	api.HandleFunc("/goals", goalHandler.Create).Methods("POST", "OPTIONS")
+	api.HandleFunc("/goals", goalHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/goals/{id}", goalHandler.Get).Methods("GET", "OPTIONS")
+	api.HandleFunc("/goals/{id}", goalHandler.Delete).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/goals/{id}/contributions", goalHandler.AddContribution).Methods("POST", "OPTIONS")
+
+	api.HandleFunc("/net-worth", netWorthHandler.Current).Methods("GET", "OPTIONS")
+	api.HandleFunc("/net-worth/history", netWorthHandler.History).Methods("GET", "OPTIONS")
+	api.HandleFunc("/net-worth/items", netWorthHandler.CreateItem).Methods("POST", "OPTIONS")
+	api.HandleFunc("/net-worth/items", netWorthHandler.ListItems).Methods("GET", "OPTIONS")
+	api.HandleFunc("/net-worth/items/{id}", netWorthHandler.DeleteItem).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/net-worth/items/{id}/valuations", netWorthHandler.AddValuation).Methods("POST", "OPTIONS")
+	api.HandleFunc("/net-worth/items/{id}/valuations", netWorthHandler.Valuations).Methods("GET", "OPTIONS")
+
+	api.HandleFunc("/metrics", metricsHandler.Get).Methods("GET", "OPTIONS")
+
+	api.HandleFunc("/settings", settingsHandler.Get).Methods("GET", "OPTIONS")
+	api.HandleFunc("/settings/timezone", settingsHandler.SetTimezone).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/settings/month-start", settingsHandler.SetMonthStart).Methods("PUT", "OPTIONS")
+
 	api.HandleFunc("/statistics", statisticsHandler.GetStatistics).Methods("POST", "OPTIONS")
+	api.HandleFunc("/forecast", forecastHandler.CashFlow).Methods("GET", "OPTIONS")
+	api.HandleFunc("/anomalies", anomalyHandler.List).Methods("GET", "OPTIONS")
+	api.HandleFunc("/anomalies/check", anomalyHandler.Check).Methods("POST", "OPTIONS")
 
 	api.HandleFunc("/export/transactions", exportHandler.ExportTransactions).Methods("POST", "OPTIONS")
+	api.HandleFunc("/export/budget-report", exportHandler.ExportBudgetReport).Methods("POST", "OPTIONS")
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		for range ticker.C {
+			if err := models.GenerateRecurringBudgets(context.Background(), time.Now()); err != nil {
+				log.Printf("Error generating recurring budgets: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		for range ticker.C {
+			if err := models.CheckBudgetAlerts(context.Background(), time.Now()); err != nil {
+				log.Printf("Error checking budget alerts: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		for range ticker.C {
+			if err := models.CheckAnomalies(context.Background(), time.Now()); err != nil {
+				log.Printf("Error checking spending anomalies: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		for range ticker.C {
+			if err := models.TakeNetWorthSnapshots(context.Background(), time.Now()); err != nil {
+				log.Printf("Error taking net worth snapshots: %v", err)
+			}
+		}
+	}()
 >>>>>>> my-feature-branch
 
 	log.Println("Server starting on port 8080...")
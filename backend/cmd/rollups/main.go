@@ -0,0 +1,75 @@
+// Команда rollups проверяет и пересобирает агрегаты статистики.
+//
+//	go run ./cmd/rollups check
+//	go run ./cmd/rollups rebuild [-user ID]
+package main
+
+import (
+	"context"
+	"finance/internal/db"
+	"finance/internal/models"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: rollups check | rebuild [-user ID]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	err := db.InitDB(
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "check":
+		mismatches, err := models.CheckRollups(ctx)
+		if err != nil {
+			log.Fatal("Failed to check rollups:", err)
+		}
+		for _, m := range mismatches {
+			category := "-"
+			if m.CategoryID != nil {
+				category = fmt.Sprint(*m.CategoryID)
+			}
+			fmt.Printf("%s user=%d date=%s category=%s type=%s total=%.2f expected=%.2f count=%d expected=%d\n",
+				m.Table, m.UserID, m.Date.Format("2006-01-02"), category, m.Type,
+				m.ActualTotal, m.ExpectedTotal, m.ActualCount, m.ExpectedCount)
+		}
+		if len(mismatches) > 0 {
+			log.Printf("Found %d mismatched rollups, run rollups rebuild to fix them", len(mismatches))
+			os.Exit(1)
+		}
+		log.Println("Rollups are consistent")
+	case "rebuild":
+		flags := flag.NewFlagSet("rebuild", flag.ExitOnError)
+		user := flags.Uint("user", 0, "rebuild only this user")
+		flags.Parse(os.Args[2:])
+
+		var userID *uint
+		if *user != 0 {
+			userID = user
+		}
+		if err := models.RebuildRollups(ctx, userID); err != nil {
+			log.Fatal("Failed to rebuild rollups:", err)
+		}
+		log.Println("Rollups rebuilt")
+	default:
+		usage()
+	}
+}
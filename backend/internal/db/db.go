@@ -70,6 +70,7 @@ func createTables() error {
             message TEXT NOT NULL,
             created_at TIMESTAMP NOT NULL,
             read BOOLEAN DEFAULT FALSE
+        )`,
 =======
             user_id INTEGER REFERENCES users(id),
             name VARCHAR(255) NOT NULL,
@@ -82,7 +83,7 @@ func createTables() error {
             amount DECIMAL(10,2) NOT NULL,
             type VARCHAR(50) NOT NULL,
             description TEXT,
-            date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+            date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
         )`,
         `CREATE TABLE IF NOT EXISTS budgets (
             id SERIAL PRIMARY KEY,
@@ -90,10 +91,188 @@ func createTables() error {
             category_id INTEGER REFERENCES categories(id),
             amount DECIMAL(10,2) NOT NULL,
             spent DECIMAL(10,2) NOT NULL DEFAULT 0,
-            start_date TIMESTAMP NOT NULL,
-            end_date TIMESTAMP NOT NULL
->>>>>>> my-feature-branch
+            start_date TIMESTAMPTZ NOT NULL,
+            end_date TIMESTAMPTZ NOT NULL
+        )`,
+        `CREATE TABLE IF NOT EXISTS budget_templates (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER REFERENCES users(id),
+            category_id INTEGER REFERENCES categories(id),
+            amount DECIMAL(10,2) NOT NULL,
+            period VARCHAR(20) NOT NULL,
+            start_day INTEGER NOT NULL DEFAULT 1,
+            rollover_mode VARCHAR(20) NOT NULL DEFAULT 'none',
+            active BOOLEAN NOT NULL DEFAULT TRUE
         )`,
+        `ALTER TABLE budgets ADD COLUMN IF NOT EXISTS template_id INTEGER REFERENCES budget_templates(id) ON DELETE SET NULL`,
+        `ALTER TABLE budgets ADD COLUMN IF NOT EXISTS rollover DECIMAL(10,2) NOT NULL DEFAULT 0`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS budgets_template_period_idx ON budgets (template_id, start_date)`,
+        `ALTER TABLE users ADD COLUMN IF NOT EXISTS budget_mode VARCHAR(20) NOT NULL DEFAULT 'standard'`,
+        `ALTER TABLE budgets ADD COLUMN IF NOT EXISTS envelope BOOLEAN NOT NULL DEFAULT FALSE`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS budgets_envelope_month_idx ON budgets (user_id, category_id, start_date) WHERE envelope`,
+        `ALTER TABLE budgets ADD COLUMN IF NOT EXISTS alert_thresholds INTEGER[] NOT NULL DEFAULT '{50,80,100}'`,
+        `CREATE TABLE IF NOT EXISTS notifications (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+            task_id INTEGER,
+            message TEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            read BOOLEAN DEFAULT FALSE
+        )`,
+        `ALTER TABLE notifications ALTER COLUMN task_id DROP NOT NULL`,
+        `ALTER TABLE notifications ADD COLUMN IF NOT EXISTS budget_id INTEGER REFERENCES budgets(id) ON DELETE CASCADE`,
+        `ALTER TABLE notifications ADD COLUMN IF NOT EXISTS alert_key VARCHAR(50)`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS notifications_budget_alert_idx ON notifications (budget_id, alert_key) WHERE budget_id IS NOT NULL`,
+        `CREATE TABLE IF NOT EXISTS budget_categories (
+            budget_id INTEGER REFERENCES budgets(id) ON DELETE CASCADE,
+            category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+            PRIMARY KEY (budget_id, category_id)
+        )`,
+        `CREATE TABLE IF NOT EXISTS recurring_transactions (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER REFERENCES users(id),
+            category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+            amount DECIMAL(10,2) NOT NULL,
+            type VARCHAR(50) NOT NULL,
+            description TEXT NOT NULL DEFAULT '',
+            period VARCHAR(20) NOT NULL,
+            start_date TIMESTAMPTZ NOT NULL
+        )`,
+        `CREATE TABLE IF NOT EXISTS spending_limits (
+            user_id INTEGER PRIMARY KEY REFERENCES users(id),
+            amount DECIMAL(10,2) NOT NULL,
+            period VARCHAR(20) NOT NULL,
+            start_day INTEGER NOT NULL DEFAULT 1
+        )`,
+        `ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL`,
+        `ALTER TABLE categories ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE`,
+        // Оформление категорий: иконка, цвет, ручной порядок и описание.
+        // Стандартные наборы категорий заполняют иконку и цвет
+        `ALTER TABLE categories ADD COLUMN IF NOT EXISTS icon VARCHAR(50) NOT NULL DEFAULT ''`,
+        `ALTER TABLE categories ADD COLUMN IF NOT EXISTS color VARCHAR(7) NOT NULL DEFAULT ''`,
+        `ALTER TABLE categories ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0`,
+        `ALTER TABLE categories ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT ''`,
+        `CREATE TABLE IF NOT EXISTS goals (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER REFERENCES users(id),
+            name VARCHAR(255) NOT NULL,
+            target_amount DECIMAL(10,2) NOT NULL,
+            deadline DATE,
+            category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+        `CREATE TABLE IF NOT EXISTS goal_contributions (
+            id SERIAL PRIMARY KEY,
+            goal_id INTEGER REFERENCES goals(id) ON DELETE CASCADE,
+            transaction_id INTEGER REFERENCES transactions(id) ON DELETE CASCADE,
+            amount DECIMAL(10,2) NOT NULL,
+            date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS goal_contributions_transaction_idx ON goal_contributions (goal_id, transaction_id) WHERE transaction_id IS NOT NULL`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS notifications_user_alert_idx ON notifications (user_id, alert_key) WHERE budget_id IS NULL AND alert_key IS NOT NULL`,
+        `CREATE TABLE IF NOT EXISTS subscription_dismissals (
+            user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+            key VARCHAR(255) NOT NULL,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (user_id, key)
+        )`,
+        `CREATE TABLE IF NOT EXISTS net_worth_items (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+            name VARCHAR(255) NOT NULL,
+            type VARCHAR(20) NOT NULL CHECK (type IN ('asset', 'liability')),
+            kind VARCHAR(20) NOT NULL DEFAULT 'other',
+            created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+        `CREATE TABLE IF NOT EXISTS valuations (
+            id SERIAL PRIMARY KEY,
+            item_id INTEGER REFERENCES net_worth_items(id) ON DELETE CASCADE,
+            value DECIMAL(14,2) NOT NULL,
+            date DATE NOT NULL,
+            UNIQUE (item_id, date)
+        )`,
+        `CREATE TABLE IF NOT EXISTS net_worth_snapshots (
+            user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+            date DATE NOT NULL,
+            assets DECIMAL(14,2) NOT NULL,
+            liabilities DECIMAL(14,2) NOT NULL,
+            PRIMARY KEY (user_id, date)
+        )`,
+        `CREATE TABLE IF NOT EXISTS daily_rollups (
+            user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+            date DATE NOT NULL,
+            category_id INTEGER,
+            type VARCHAR(50) NOT NULL,
+            total DECIMAL(14,2) NOT NULL DEFAULT 0,
+            count INTEGER NOT NULL DEFAULT 0
+        )`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS daily_rollups_key_idx
+         ON daily_rollups (user_id, date, (COALESCE(category_id, 0)), type)`,
+        `CREATE TABLE IF NOT EXISTS monthly_rollups (
+            user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+            month DATE NOT NULL,
+            category_id INTEGER,
+            type VARCHAR(50) NOT NULL,
+            total DECIMAL(14,2) NOT NULL DEFAULT 0,
+            count INTEGER NOT NULL DEFAULT 0
+        )`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS monthly_rollups_key_idx
+         ON monthly_rollups (user_id, month, (COALESCE(category_id, 0)), type)`,
+        // Разовые миграции данных, выполняемые при запуске (см. RunMigration)
+        `CREATE TABLE IF NOT EXISTS schema_migrations (
+            key VARCHAR(64) PRIMARY KEY,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+        `ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC'`,
+        `ALTER TABLE users ADD COLUMN IF NOT EXISTS month_start_day INTEGER NOT NULL DEFAULT 1
+         CHECK (month_start_day BETWEEN 1 AND 28)`,
+        // Даты без зоны переводятся в TIMESTAMPTZ в зоне сессии базы. Агрегаты, собранные
+        // по дням в зоне сервера, очищаются и пересобираются при запуске по зонам пользователей
+        `DO $$
+        DECLARE
+            col record;
+        BEGIN
+            FOR col IN
+                SELECT table_name, column_name
+                FROM information_schema.columns
+                WHERE table_schema = current_schema()
+                AND data_type = 'timestamp without time zone'
+                AND (table_name, column_name) IN (
+                    ('transactions', 'date'),
+                    ('budgets', 'start_date'),
+                    ('budgets', 'end_date'),
+                    ('recurring_transactions', 'start_date'),
+                    ('goals', 'created_at'),
+                    ('goal_contributions', 'date'),
+                    ('subscription_dismissals', 'created_at'),
+                    ('net_worth_items', 'created_at')
+                )
+            LOOP
+                EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ', col.table_name, col.column_name);
+                IF col.table_name = 'transactions' THEN
+                    DELETE FROM daily_rollups;
+                    DELETE FROM monthly_rollups;
+                    DELETE FROM schema_migrations WHERE key = 'rollups_v1';
+                END IF;
+            END LOOP;
+        END $$`,
+        // NOT VALID: ограничения проверяют новые строки, не падая на старых данных
+        `DO $$ BEGIN
+            ALTER TABLE categories ADD CONSTRAINT categories_type_check
+                CHECK (type IN ('income', 'expense')) NOT VALID;
+        EXCEPTION WHEN duplicate_object THEN NULL;
+        END $$`,
+        `DO $$ BEGIN
+            ALTER TABLE transactions ADD CONSTRAINT transactions_type_check
+                CHECK (type IN ('income', 'expense')) NOT VALID;
+        EXCEPTION WHEN duplicate_object THEN NULL;
+        END $$`,
+        `DO $$ BEGIN
+            ALTER TABLE recurring_transactions ADD CONSTRAINT recurring_transactions_type_check
+                CHECK (type IN ('income', 'expense')) NOT VALID;
+        EXCEPTION WHEN duplicate_object THEN NULL;
+        END $$`,
+>>>>>>> my-feature-branch
     }
 
     for _, query := range queries {
@@ -0,0 +1,26 @@
+package db
+
+import "context"
+
+// RunMigration выполняет разовую миграцию данных key, если она еще не применена.
+// Миграция и отметка о ней сохраняются в одной транзакции
+func RunMigration(ctx context.Context, key string, fn func(tx Querier) error) error {
+    return WithTx(ctx, func(tx Querier) error {
+        result, err := tx.ExecContext(ctx,
+            "INSERT INTO schema_migrations (key) VALUES ($1) ON CONFLICT (key) DO NOTHING",
+            key,
+        )
+        if err != nil {
+            return err
+        }
+
+        applied, err := result.RowsAffected()
+        if err != nil {
+            return err
+        }
+        if applied == 0 {
+            return nil
+        }
+        return fn(tx)
+    })
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+    "context"
+    "database/sql"
+)
+
+// Querier - общий интерфейс для *sql.DB и *sql.Tx, чтобы модели могли
+// выполнять запросы как вне транзакции, так и внутри нее
+type Querier interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// WithTx выполняет fn в одной транзакции: коммит при успехе,
+// откат при ошибке или панике
+func WithTx(ctx context.Context, fn func(tx Querier) error) (err error) {
+    tx, err := DB.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+
+    defer func() {
+        if p := recover(); p != nil {
+            tx.Rollback()
+            panic(p)
+        }
+        if err != nil {
+            tx.Rollback()
+            return
+        }
+        err = tx.Commit()
+    }()
+
+    err = fn(tx)
+    return err
+}
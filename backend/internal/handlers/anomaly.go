@@ -0,0 +1,62 @@
+package handlers
+
+import (
+    "encoding/json"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+const defaultAnomalyDays = 30
+
+type AnomalyHandler struct{}
+
+func NewAnomalyHandler() *AnomalyHandler {
+    return &AnomalyHandler{}
+}
+
+// List возвращает аномалии расходов за ?days= последних дней (по умолчанию 30)
+func (h *AnomalyHandler) List(w http.ResponseWriter, r *http.Request) {
+    days := defaultAnomalyDays
+    if value := r.URL.Query().Get("days"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 || parsed > 365 {
+            http.Error(w, "Days must be between 1 and 365", http.StatusBadRequest)
+            return
+        }
+        days = parsed
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    loc, ok := userLocation(w, userID)
+    if !ok {
+        return
+    }
+
+    anomalies, err := models.DetectAnomalies(userID, time.Now().In(loc), days)
+    if err != nil {
+        log.Printf("Error detecting anomalies: %v", err)
+        http.Error(w, "Could not detect anomalies", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(anomalies)
+}
+
+// Check создает уведомления по аномалиям за последнюю неделю
+func (h *AnomalyHandler) Check(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if err := models.CheckUserAnomalies(r.Context(), userID, time.Now()); err != nil {
+        log.Printf("Error checking anomalies: %v", err)
+        http.Error(w, "Could not check anomalies", http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
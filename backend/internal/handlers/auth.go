@@ -4,6 +4,8 @@ import (
     "encoding/json"
     "net/http"
     "github.com/golang-jwt/jwt/v5"
+    "log"
+    "strings"
     "time"
     "finance/internal/models"
 )
@@ -21,6 +23,8 @@ type RegisterRequest struct {
     Email    string `json:"email"`
     Password string `json:"password"`
     Name     string `json:"name"`
+    // Язык стандартного набора категорий: ru или en
+    Locale string `json:"locale,omitempty"`
 }
 
 type AuthResponse struct {
@@ -79,6 +83,13 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    // Без категорий новый пользователь не сможет разнести транзакции,
+    // но ошибка заполнения не должна срывать регистрацию
+    locale := presetLocale(req.Locale, r.Header.Get("Accept-Language"))
+    if err := models.ApplyCategoryPreset(r.Context(), user.ID, locale, false); err != nil {
+        log.Printf("Error seeding default categories: %v", err)
+    }
+
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
         "user_id": user.ID,
         "email": user.Email,
@@ -92,4 +103,16 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
     }
 
     json.NewEncoder(w).Encode(AuthResponse{Token: tokenString})
-} 
\ No newline at end of file
+}
+
+// presetLocale выбирает язык стандартного набора: явно указанный,
+// иначе по заголовку Accept-Language, по умолчанию русский
+func presetLocale(locale, acceptLanguage string) string {
+    if models.ValidPresetLocale(locale) {
+        return locale
+    }
+    if strings.HasPrefix(strings.ToLower(acceptLanguage), models.PresetLocaleEnglish) {
+        return models.PresetLocaleEnglish
+    }
+    return models.PresetLocaleRussian
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
     "net/http"
     "finance/internal/models"
     "github.com/golang-jwt/jwt/v5"
@@ -14,10 +15,33 @@ import (
 type BudgetHandler struct{}
 
 type CreateBudgetRequest struct {
-    CategoryID uint      `json:"category_id"`
-    Amount     float64   `json:"amount"`
-    StartDate  time.Time `json:"start_date"`
-    EndDate    time.Time `json:"end_date"`
+    CategoryID      uint      `json:"category_id"`
+    CategoryIDs     []uint    `json:"category_ids,omitempty"`
+    Amount          float64   `json:"amount"`
+    StartDate       time.Time `json:"start_date"`
+    EndDate         time.Time `json:"end_date"`
+    AlertThresholds []int64   `json:"alert_thresholds,omitempty"`
+}
+
+type UpdateBudgetRequest struct {
+    CategoryID      *uint      `json:"category_id"`
+    CategoryIDs     []uint     `json:"category_ids"`
+    Amount          *float64   `json:"amount"`
+    StartDate       *time.Time `json:"start_date"`
+    EndDate         *time.Time `json:"end_date"`
+    AlertThresholds []int64    `json:"alert_thresholds"`
+}
+
+type SetSpendingLimitRequest struct {
+    Amount   float64 `json:"amount"`
+    Period   string  `json:"period"`
+    StartDay int     `json:"start_day"`
+}
+
+type BudgetReportRequest struct {
+    StartDate   time.Time `json:"start_date"`
+    EndDate     time.Time `json:"end_date"`
+    Granularity string    `json:"granularity"`
 }
 
 func NewBudgetHandler() *BudgetHandler {
@@ -39,13 +63,33 @@ func (h *BudgetHandler) Create(w http.ResponseWriter, r *http.Request) {
 
     log.Printf("Creating budget for user %d", userID)
 
-    budget, err := models.CreateBudget(userID, req.CategoryID, req.Amount, req.StartDate, req.EndDate)
+    // Основная категория идет первой, остальные бюджет покрывает дополнительно
+    categoryIDs := req.CategoryIDs
+    if req.CategoryID != 0 {
+        categoryIDs = append([]uint{req.CategoryID}, categoryIDs...)
+    }
+
+    if !validateBudget(w, userID, categoryIDs, req.Amount, req.StartDate, req.EndDate) ||
+        !validateAlertThresholds(w, req.AlertThresholds) {
+        return
+    }
+
+    budget, err := models.CreateBudget(r.Context(), userID, categoryIDs, req.Amount, req.StartDate, req.EndDate)
     if err != nil {
         log.Printf("Error creating budget: %v", err)
         http.Error(w, "Could not create budget", http.StatusInternalServerError)
         return
     }
 
+    if req.AlertThresholds != nil {
+        if err := models.SetBudgetAlertThresholds(budget.ID, userID, req.AlertThresholds); err != nil {
+            log.Printf("Error setting budget alert thresholds: %v", err)
+            http.Error(w, "Could not set alert thresholds", http.StatusInternalServerError)
+            return
+        }
+        budget.AlertThresholds = req.AlertThresholds
+    }
+
     log.Printf("Successfully created budget: %+v", budget)
     json.NewEncoder(w).Encode(budget)
 }
@@ -63,6 +107,240 @@ func (h *BudgetHandler) List(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(budgets)
 }
 
+func (h *BudgetHandler) History(w http.ResponseWriter, r *http.Request) {
+    categoryID, err := strconv.ParseUint(r.URL.Query().Get("category_id"), 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid category ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    budgets, err := models.GetBudgetHistory(userID, uint(categoryID))
+    if err != nil {
+        http.Error(w, "Could not get budget history", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(budgets)
+}
+
+func (h *BudgetHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
+    var req SetSpendingLimitRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if req.Period == "" {
+        req.Period = models.PeriodMonthly
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if req.StartDay == 0 {
+        startDay, ok := userPeriodStartDay(w, userID, req.Period)
+        if !ok {
+            return
+        }
+        req.StartDay = startDay
+    }
+
+    if req.Amount <= 0 {
+        http.Error(w, "Amount must be positive", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidPeriod(req.Period) {
+        http.Error(w, "Period must be one of weekly, monthly, quarterly, yearly", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidStartDay(req.Period, req.StartDay) {
+        http.Error(w, "Invalid start day for period", http.StatusBadRequest)
+        return
+    }
+
+    limit, err := models.SetSpendingLimit(userID, req.Amount, req.Period, req.StartDay)
+    if err != nil {
+        log.Printf("Error setting spending limit: %v", err)
+        http.Error(w, "Could not set spending limit", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(limit)
+}
+
+func (h *BudgetHandler) Summary(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    loc, ok := userLocation(w, userID)
+    if !ok {
+        return
+    }
+
+    summary, err := models.GetSpendingSummary(userID, time.Now().In(loc))
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Spending limit is not set", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        log.Printf("Error getting spending summary: %v", err)
+        http.Error(w, "Could not get spending summary", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(summary)
+}
+
+func (h *BudgetHandler) Report(w http.ResponseWriter, r *http.Request) {
+    var req BudgetReportRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    report, ok := getBudgetReport(w, userID, req)
+    if !ok {
+        return
+    }
+
+    json.NewEncoder(w).Encode(report)
+}
+
+// getBudgetReport проверяет параметры отчета и строит его, при ошибке пишет ее в ответ
+func getBudgetReport(w http.ResponseWriter, userID uint, req BudgetReportRequest) ([]models.BudgetReportPeriod, bool) {
+    if req.Granularity == "" {
+        req.Granularity = "month"
+    }
+
+    period, ok := models.ReportPeriod(req.Granularity)
+    if !ok {
+        http.Error(w, "Granularity must be one of month, quarter, year", http.StatusBadRequest)
+        return nil, false
+    }
+    if !req.EndDate.After(req.StartDate) {
+        http.Error(w, "End date must be after start date", http.StatusBadRequest)
+        return nil, false
+    }
+
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return nil, false
+    }
+
+    // Периоды отчета считаются в часовом поясе пользователя
+    loc := settings.Location()
+    report, err := models.GetBudgetReport(userID, req.StartDate.In(loc), req.EndDate.In(loc), period, settings.MonthStartDay)
+    if err != nil {
+        log.Printf("Error building budget report: %v", err)
+        http.Error(w, "Could not get budget report", http.StatusInternalServerError)
+        return nil, false
+    }
+    return report, true
+}
+
+func (h *BudgetHandler) Recalculate(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    updated, err := models.RecalculateBudgets(r.Context(), &userID)
+    if err != nil {
+        log.Printf("Error recalculating budgets: %v", err)
+        http.Error(w, "Could not recalculate budgets", http.StatusInternalServerError)
+        return
+    }
+
+    log.Printf("Recalculated %d budgets for user %d", updated, userID)
+    json.NewEncoder(w).Encode(map[string]int64{"updated": updated})
+}
+
+func (h *BudgetHandler) Update(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    budgetID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+        return
+    }
+
+    var req UpdateBudgetRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        log.Printf("Error decoding request body: %v", err)
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    budget, err := models.GetBudget(uint(budgetID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Budget not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not get budget", http.StatusInternalServerError)
+        return
+    }
+
+    categoryIDs := make([]uint, len(budget.CategoryIDs))
+    for i, id := range budget.CategoryIDs {
+        categoryIDs[i] = uint(id)
+    }
+
+    // PATCH меняет только переданные поля, PUT передает их все
+    if req.CategoryIDs != nil {
+        categoryIDs = req.CategoryIDs
+    }
+    if req.CategoryID != nil {
+        if req.CategoryIDs != nil {
+            categoryIDs = append([]uint{*req.CategoryID}, categoryIDs...)
+        } else {
+            categoryIDs[0] = *req.CategoryID
+        }
+    }
+    if req.Amount != nil {
+        budget.Amount = *req.Amount
+    }
+    if req.StartDate != nil {
+        budget.StartDate = *req.StartDate
+    }
+    if req.EndDate != nil {
+        budget.EndDate = *req.EndDate
+    }
+
+    if !validateBudget(w, userID, categoryIDs, budget.Amount, budget.StartDate, budget.EndDate) ||
+        !validateAlertThresholds(w, req.AlertThresholds) {
+        return
+    }
+
+    budget, err = models.UpdateBudget(r.Context(), budget.ID, userID, categoryIDs, budget.Amount, budget.StartDate, budget.EndDate)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Budget not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        log.Printf("Error updating budget: %v", err)
+        http.Error(w, "Could not update budget", http.StatusInternalServerError)
+        return
+    }
+
+    if req.AlertThresholds != nil {
+        if err := models.SetBudgetAlertThresholds(budget.ID, userID, req.AlertThresholds); err != nil {
+            log.Printf("Error setting budget alert thresholds: %v", err)
+            http.Error(w, "Could not set alert thresholds", http.StatusInternalServerError)
+            return
+        }
+        budget.AlertThresholds = req.AlertThresholds
+    }
+
+    json.NewEncoder(w).Encode(budget)
+}
+
 func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     budgetID, err := strconv.ParseUint(vars["id"], 10, 32)
@@ -71,11 +349,76 @@ func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    err = models.DeleteBudget(uint(budgetID))
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err = models.DeleteBudget(uint(budgetID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Budget not found", http.StatusNotFound)
+        return
+    }
     if err != nil {
         http.Error(w, "Could not delete budget", http.StatusInternalServerError)
         return
     }
 
     w.WriteHeader(http.StatusOK)
-} 
\ No newline at end of file
+}
+
+// validateBudget проверяет поля бюджета и пишет ошибку в ответ,
+// если они некорректны
+func validateBudget(w http.ResponseWriter, userID uint, categoryIDs []uint, amount float64, startDate, endDate time.Time) bool {
+    if amount <= 0 {
+        http.Error(w, "Amount must be positive", http.StatusBadRequest)
+        return false
+    }
+
+    if !endDate.After(startDate) {
+        http.Error(w, "End date must be after start date", http.StatusBadRequest)
+        return false
+    }
+
+    if len(categoryIDs) == 0 {
+        http.Error(w, "At least one category is required", http.StatusBadRequest)
+        return false
+    }
+
+    for _, categoryID := range categoryIDs {
+        if !validateBudgetCategory(w, userID, categoryID) {
+            return false
+        }
+    }
+    return true
+}
+
+// validateAlertThresholds проверяет пороги уведомлений в процентах от суммы бюджета
+func validateAlertThresholds(w http.ResponseWriter, thresholds []int64) bool {
+    for _, threshold := range thresholds {
+        if threshold <= 0 || threshold > 1000 {
+            http.Error(w, "Alert thresholds must be between 1 and 1000 percent", http.StatusBadRequest)
+            return false
+        }
+    }
+    return true
+}
+
+// validateBudgetCategory проверяет, что категория принадлежит пользователю
+// и является категорией расходов
+func validateBudgetCategory(w http.ResponseWriter, userID, categoryID uint) bool {
+    category, err := models.GetCategory(categoryID, userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return false
+    }
+    if err != nil {
+        http.Error(w, "Could not get category", http.StatusInternalServerError)
+        return false
+    }
+
+    if category.Type != models.TransactionTypeExpense {
+        http.Error(w, "Budget category must be an expense category", http.StatusBadRequest)
+        return false
+    }
+
+    return true
+}
\ No newline at end of file
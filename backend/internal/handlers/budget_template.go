@@ -0,0 +1,121 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/gorilla/mux"
+    "strconv"
+    "time"
+    "log"
+)
+
+type BudgetTemplateHandler struct{}
+
+type CreateBudgetTemplateRequest struct {
+    CategoryID   uint    `json:"category_id"`
+    Amount       float64 `json:"amount"`
+    Period       string  `json:"period"`
+    StartDay     int     `json:"start_day"`
+    RolloverMode string  `json:"rollover_mode"`
+}
+
+func NewBudgetTemplateHandler() *BudgetTemplateHandler {
+    return &BudgetTemplateHandler{}
+}
+
+func (h *BudgetTemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+    var req CreateBudgetTemplateRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        log.Printf("Error decoding request body: %v", err)
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if req.StartDay == 0 && models.ValidPeriod(req.Period) {
+        startDay, ok := userPeriodStartDay(w, userID, req.Period)
+        if !ok {
+            return
+        }
+        req.StartDay = startDay
+    }
+    if req.RolloverMode == "" {
+        req.RolloverMode = models.RolloverNone
+    }
+
+    if req.Amount <= 0 {
+        http.Error(w, "Amount must be positive", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidPeriod(req.Period) {
+        http.Error(w, "Period must be one of weekly, monthly, quarterly, yearly", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidStartDay(req.Period, req.StartDay) {
+        http.Error(w, "Invalid start day for period", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidRolloverMode(req.RolloverMode) {
+        http.Error(w, "Rollover mode must be one of none, unspent, all", http.StatusBadRequest)
+        return
+    }
+    if !validateBudgetCategory(w, userID, req.CategoryID) {
+        return
+    }
+
+    template, err := models.CreateBudgetTemplate(userID, req.CategoryID, req.Amount, req.Period, req.StartDay, req.RolloverMode)
+    if err != nil {
+        log.Printf("Error creating budget template: %v", err)
+        http.Error(w, "Could not create budget template", http.StatusInternalServerError)
+        return
+    }
+
+    // Сразу создаем бюджет на текущий период
+    if err := models.GenerateTemplateBudgets(r.Context(), *template, time.Now()); err != nil {
+        log.Printf("Error generating recurring budgets: %v", err)
+    }
+
+    json.NewEncoder(w).Encode(template)
+}
+
+func (h *BudgetTemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    templates, err := models.GetUserBudgetTemplates(userID)
+    if err != nil {
+        http.Error(w, "Could not get budget templates", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(templates)
+}
+
+func (h *BudgetTemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    templateID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid template ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err = models.DeleteBudgetTemplate(uint(templateID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Budget template not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not delete budget template", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
@@ -8,8 +8,12 @@ import (
     "github.com/gorilla/mux"
     "todo-app/internal/models"
 =======
+    "errors"
     "finance/internal/models"
     "github.com/golang-jwt/jwt/v5"
+    "github.com/gorilla/mux"
+    "strconv"
+    "strings"
 >>>>>>> my-feature-branch
 )
 
@@ -18,11 +22,41 @@ type CategoryHandler struct{}
 type CreateCategoryRequest struct {
     Name string `json:"name"`
 <<<<<<< HEAD
+}
 =======
-    Type string `json:"type"`
->>>>>>> my-feature-branch
+    Type        string `json:"type"`
+    ParentID    *uint  `json:"parent_id"`
+    Icon        string `json:"icon"`
+    Color       string `json:"color"`
+    Description string `json:"description"`
+}
+
+type MoveCategoryRequest struct {
+    ParentID *uint `json:"parent_id"`
+}
+
+type UpdateCategoryRequest struct {
+    Name        *string `json:"name"`
+    Icon        *string `json:"icon"`
+    Color       *string `json:"color"`
+    Description *string `json:"description"`
+    Archived    *bool   `json:"archived"`
 }
 
+type ReorderCategoriesRequest struct {
+    CategoryIDs []uint `json:"category_ids"`
+}
+
+type MergeCategoryRequest struct {
+    TargetID uint `json:"target_id"`
+}
+
+type ApplyPresetRequest struct {
+    Locale string `json:"locale"`
+    Reset  bool   `json:"reset"`
+}
+>>>>>>> my-feature-branch
+
 func NewCategoryHandler() *CategoryHandler {
     return &CategoryHandler{}
 }
@@ -51,10 +85,27 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
     userID := getUserIDFromToken(r)
     category, err := models.CreateCategory(req.Name, userID)
 =======
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" {
+        http.Error(w, "Name is required", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidTransactionType(req.Type) {
+        http.Error(w, "Type must be one of income, expense", http.StatusBadRequest)
+        return
+    }
+    if !validateCategoryMeta(w, req.Icon, req.Color, req.Description) {
+        return
+    }
+
     claims := r.Context().Value("claims").(jwt.MapClaims)
     userID := uint(claims["user_id"].(float64))
 
-    category, err := models.CreateCategory(userID, req.Name, req.Type)
+    if req.ParentID != nil && !validateRelatedCategory(w, userID, *req.ParentID, req.Type, "Parent category") {
+        return
+    }
+
+    category, err := models.CreateCategory(userID, req.Name, req.Type, req.ParentID, req.Icon, req.Color, req.Description)
 >>>>>>> my-feature-branch
     if err != nil {
         http.Error(w, "Could not create category", http.StatusInternalServerError)
@@ -126,6 +177,7 @@ func (h *CategoryHandler) UpdateTaskCategory(w http.ResponseWriter, r *http.Requ
     }
 
     w.WriteHeader(http.StatusOK)
+}
 =======
     json.NewEncoder(w).Encode(category)
 }
@@ -140,6 +192,349 @@ func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    // Архивные категории не показываются при выборе, если их не запросили явно
+    if r.URL.Query().Get("include_archived") != "true" {
+        active := make([]models.Category, 0, len(categories))
+        for _, c := range categories {
+            if !c.Archived {
+                active = append(active, c)
+            }
+        }
+        categories = active
+    }
+
     json.NewEncoder(w).Encode(categories)
+}
+
+func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    categoryID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid category ID", http.StatusBadRequest)
+        return
+    }
+
+    var req UpdateCategoryRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    category, err := models.GetCategory(uint(categoryID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not get category", http.StatusInternalServerError)
+        return
+    }
+
+    if req.Name != nil {
+        category.Name = strings.TrimSpace(*req.Name)
+    }
+    if req.Icon != nil {
+        category.Icon = *req.Icon
+    }
+    if req.Color != nil {
+        category.Color = *req.Color
+    }
+    if req.Description != nil {
+        category.Description = *req.Description
+    }
+    if req.Archived != nil {
+        category.Archived = *req.Archived
+    }
+    if category.Name == "" {
+        http.Error(w, "Name is required", http.StatusBadRequest)
+        return
+    }
+    if !validateCategoryMeta(w, category.Icon, category.Color, category.Description) {
+        return
+    }
+
+    category, err = models.UpdateCategory(category.ID, userID, category.Name, category.Icon, category.Color, category.Description, category.Archived)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not update category", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(category)
+}
+
+// Delete удаляет категорию. С параметром reassign_to транзакции и бюджеты
+// переходят в указанную категорию, иначе остаются без категории
+func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    categoryID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid category ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    category, err := models.GetCategory(uint(categoryID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not get category", http.StatusInternalServerError)
+        return
+    }
+
+    var reassignTo *uint
+    if value := r.URL.Query().Get("reassign_to"); value != "" {
+        targetID, err := strconv.ParseUint(value, 10, 32)
+        if err != nil || uint(targetID) == category.ID {
+            http.Error(w, "Invalid reassign_to category", http.StatusBadRequest)
+            return
+        }
+        if !validateRelatedCategory(w, userID, uint(targetID), category.Type, "Target category") {
+            return
+        }
+        id := uint(targetID)
+        reassignTo = &id
+    }
+
+    err = models.DeleteCategory(r.Context(), category.ID, userID, reassignTo)
+    if errors.Is(err, models.ErrCategoryCycle) {
+        http.Error(w, "Category cannot be reassigned to its subcategory", http.StatusBadRequest)
+        return
+    }
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not delete category", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
+
+// Merge сливает категорию из пути в target_id: все ее данные переходят в target_id
+func (h *CategoryHandler) Merge(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    categoryID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid category ID", http.StatusBadRequest)
+        return
+    }
+
+    var req MergeCategoryRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    source, err := models.GetCategory(uint(categoryID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not get category", http.StatusInternalServerError)
+        return
+    }
+
+    if req.TargetID == source.ID {
+        http.Error(w, "Category cannot be merged into itself", http.StatusBadRequest)
+        return
+    }
+    if !validateRelatedCategory(w, userID, req.TargetID, source.Type, "Target category") {
+        return
+    }
+
+    target, err := models.MergeCategories(r.Context(), userID, source.ID, req.TargetID)
+    if errors.Is(err, models.ErrCategoryCycle) {
+        http.Error(w, "Category cannot be merged into its subcategory", http.StatusBadRequest)
+        return
+    }
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not merge categories", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(target)
+}
+
+// Move переносит категорию под другого родителя или в корень (parent_id: null)
+func (h *CategoryHandler) Move(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    categoryID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid category ID", http.StatusBadRequest)
+        return
+    }
+
+    var req MoveCategoryRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    category, err := models.GetCategory(uint(categoryID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not move category", http.StatusInternalServerError)
+        return
+    }
+
+    if req.ParentID != nil && !validateRelatedCategory(w, userID, *req.ParentID, category.Type, "Parent category") {
+        return
+    }
+
+    category, err = models.MoveCategory(r.Context(), category.ID, userID, req.ParentID)
+    if errors.Is(err, models.ErrCategoryCycle) {
+        http.Error(w, "Category cannot be moved under itself or its subcategory", http.StatusBadRequest)
+        return
+    }
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not move category", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(category)
+}
+
+// ApplyPreset добавляет недостающие категории стандартного набора,
+// а с reset возвращает набор к исходному виду, архивируя остальные категории
+func (h *CategoryHandler) ApplyPreset(w http.ResponseWriter, r *http.Request) {
+    var req ApplyPresetRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if req.Locale == "" {
+        req.Locale = presetLocale("", r.Header.Get("Accept-Language"))
+    }
+    if !models.ValidPresetLocale(req.Locale) {
+        http.Error(w, "Locale must be one of ru, en", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if err := models.ApplyCategoryPreset(r.Context(), userID, req.Locale, req.Reset); err != nil {
+        http.Error(w, "Could not apply category preset", http.StatusInternalServerError)
+        return
+    }
+
+    categories, err := models.GetUserCategories(userID)
+    if err != nil {
+        http.Error(w, "Could not get categories", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(categories)
+}
+
+// Reorder задает ручной порядок категорий в списках
+func (h *CategoryHandler) Reorder(w http.ResponseWriter, r *http.Request) {
+    var req ReorderCategoriesRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if len(req.CategoryIDs) == 0 {
+        http.Error(w, "Category IDs are required", http.StatusBadRequest)
+        return
+    }
+    seen := make(map[uint]bool)
+    for _, id := range req.CategoryIDs {
+        if seen[id] {
+            http.Error(w, "Category IDs must be unique", http.StatusBadRequest)
+            return
+        }
+        seen[id] = true
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err := models.ReorderCategories(r.Context(), userID, req.CategoryIDs)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not reorder categories", http.StatusInternalServerError)
+        return
+    }
+
+    categories, err := models.GetUserCategories(userID)
+    if err != nil {
+        http.Error(w, "Could not get categories", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(categories)
+}
+
+// validateCategoryMeta проверяет оформление и описание категории
+func validateCategoryMeta(w http.ResponseWriter, icon, color, description string) bool {
+    if !models.ValidCategoryIcon(icon) {
+        http.Error(w, "Icon must be a Material icon name, e.g. shopping_cart", http.StatusBadRequest)
+        return false
+    }
+    if !models.ValidCategoryColor(color) {
+        http.Error(w, "Color must be in #RRGGBB format", http.StatusBadRequest)
+        return false
+    }
+    if !models.ValidCategoryDescription(description) {
+        http.Error(w, "Description is too long", http.StatusBadRequest)
+        return false
+    }
+    return true
+}
+
+// validateRelatedCategory проверяет, что связанная категория (родитель или цель слияния)
+// принадлежит пользователю и имеет тот же тип. role используется в тексте ошибки
+func validateRelatedCategory(w http.ResponseWriter, userID, id uint, categoryType, role string) bool {
+    related, err := models.GetCategory(id, userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, role+" not found", http.StatusNotFound)
+        return false
+    }
+    if err != nil {
+        http.Error(w, "Could not get category", http.StatusInternalServerError)
+        return false
+    }
+    if related.Type != categoryType {
+        http.Error(w, role+" must have the same type", http.StatusBadRequest)
+        return false
+    }
+    return true
+}
 >>>>>>> my-feature-branch
-} 
\ No newline at end of file
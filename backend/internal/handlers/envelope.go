@@ -0,0 +1,192 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "time"
+    "log"
+)
+
+type EnvelopeHandler struct{}
+
+type SetBudgetModeRequest struct {
+    Mode string `json:"mode"`
+}
+
+type AssignEnvelopeRequest struct {
+    CategoryID uint    `json:"category_id"`
+    Month      string  `json:"month"`
+    Amount     float64 `json:"amount"`
+}
+
+type MoveEnvelopeRequest struct {
+    FromCategoryID uint    `json:"from_category_id"`
+    ToCategoryID   uint    `json:"to_category_id"`
+    Month          string  `json:"month"`
+    Amount         float64 `json:"amount"`
+}
+
+func NewEnvelopeHandler() *EnvelopeHandler {
+    return &EnvelopeHandler{}
+}
+
+// parseMonth разбирает месяц в формате 2006-01, пустая строка - текущий месяц.
+// Финансовый месяц 2006-01 начинается в день начала месяца пользователя в его часовом поясе
+func parseMonth(month string, settings *models.UserSettings) (time.Time, error) {
+    loc := settings.Location()
+    if month == "" {
+        return time.Now().In(loc), nil
+    }
+    parsed, err := time.ParseInLocation("2006-01", month, loc)
+    if err != nil {
+        return time.Time{}, err
+    }
+    return parsed.AddDate(0, 0, settings.MonthStartDay-1), nil
+}
+
+// envelopeMonth проверяет режим конвертов и разбирает месяц запроса.
+// При ошибке пишет ее в ответ
+func envelopeMonth(w http.ResponseWriter, userID uint, month string) (time.Time, int, bool) {
+    if !requireEnvelopeMode(w, userID) {
+        return time.Time{}, 0, false
+    }
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return time.Time{}, 0, false
+    }
+    parsed, err := parseMonth(month, settings)
+    if err != nil {
+        http.Error(w, "Invalid month format", http.StatusBadRequest)
+        return time.Time{}, 0, false
+    }
+    return parsed, settings.MonthStartDay, true
+}
+
+// requireEnvelopeMode пишет ошибку в ответ, если у пользователя не включен режим конвертов
+func requireEnvelopeMode(w http.ResponseWriter, userID uint) bool {
+    mode, err := models.GetUserBudgetMode(userID)
+    if err != nil {
+        http.Error(w, "Could not get budget mode", http.StatusInternalServerError)
+        return false
+    }
+    if mode != models.BudgetModeEnvelope {
+        http.Error(w, "Envelope budgeting is not enabled", http.StatusConflict)
+        return false
+    }
+    return true
+}
+
+func (h *EnvelopeHandler) SetMode(w http.ResponseWriter, r *http.Request) {
+    var req SetBudgetModeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if !models.ValidBudgetMode(req.Mode) {
+        http.Error(w, "Mode must be one of standard, envelope", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if err := models.SetUserBudgetMode(userID, req.Mode); err != nil {
+        http.Error(w, "Could not set budget mode", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(req)
+}
+
+func (h *EnvelopeHandler) Summary(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    month, monthStartDay, ok := envelopeMonth(w, userID, r.URL.Query().Get("month"))
+    if !ok {
+        return
+    }
+
+    summary, err := models.GetEnvelopeSummary(userID, month, monthStartDay)
+    if err != nil {
+        log.Printf("Error getting envelope summary: %v", err)
+        http.Error(w, "Could not get envelopes", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(summary)
+}
+
+func (h *EnvelopeHandler) Assign(w http.ResponseWriter, r *http.Request) {
+    var req AssignEnvelopeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if req.Amount < 0 {
+        http.Error(w, "Amount must not be negative", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    month, monthStartDay, ok := envelopeMonth(w, userID, req.Month)
+    if !ok || !validateBudgetCategory(w, userID, req.CategoryID) {
+        return
+    }
+
+    envelope, err := models.AssignEnvelope(r.Context(), userID, req.CategoryID, month, monthStartDay, req.Amount)
+    if err != nil {
+        log.Printf("Error assigning envelope: %v", err)
+        http.Error(w, "Could not assign envelope", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(envelope)
+}
+
+func (h *EnvelopeHandler) Move(w http.ResponseWriter, r *http.Request) {
+    var req MoveEnvelopeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if req.Amount <= 0 {
+        http.Error(w, "Amount must be positive", http.StatusBadRequest)
+        return
+    }
+    if req.FromCategoryID == req.ToCategoryID {
+        http.Error(w, "Source and target envelopes must differ", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    month, monthStartDay, ok := envelopeMonth(w, userID, req.Month)
+    if !ok ||
+        !validateBudgetCategory(w, userID, req.FromCategoryID) ||
+        !validateBudgetCategory(w, userID, req.ToCategoryID) {
+        return
+    }
+
+    err := models.MoveEnvelopeFunds(r.Context(), userID, req.FromCategoryID, req.ToCategoryID, month, monthStartDay, req.Amount)
+    if errors.Is(err, models.ErrInsufficientFunds) {
+        http.Error(w, "Not enough money assigned to source envelope", http.StatusBadRequest)
+        return
+    }
+    if err != nil {
+        log.Printf("Error moving envelope funds: %v", err)
+        http.Error(w, "Could not move envelope funds", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
\ No newline at end of file
@@ -83,4 +83,56 @@ func (h *ExportHandler) ExportTransactions(w http.ResponseWriter, r *http.Reques
             return
         }
     }
-} 
\ No newline at end of file
+}
+
+func (h *ExportHandler) ExportBudgetReport(w http.ResponseWriter, r *http.Request) {
+    var req BudgetReportRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    report, ok := getBudgetReport(w, userID, req)
+    if !ok {
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/csv")
+    w.Header().Set("Content-Disposition", "attachment; filename=budget_report.csv")
+
+    csvWriter := csv.NewWriter(w)
+    defer csvWriter.Flush()
+
+    headers := []string{"Начало периода", "Конец периода", "Категория", "Бюджет", "Факт", "Отклонение", "Отклонение, %"}
+    if err := csvWriter.Write(headers); err != nil {
+        http.Error(w, "Could not write CSV headers", http.StatusInternalServerError)
+        return
+    }
+
+    for _, period := range report {
+        for _, line := range period.Lines {
+            variancePercent := ""
+            if line.VariancePercent != nil {
+                variancePercent = strconv.FormatFloat(*line.VariancePercent, 'f', 1, 64)
+            }
+
+            record := []string{
+                period.StartDate.Format("02.01.2006"),
+                period.EndDate.Format("02.01.2006"),
+                line.CategoryName,
+                strconv.FormatFloat(line.Budgeted, 'f', 2, 64),
+                strconv.FormatFloat(line.Actual, 'f', 2, 64),
+                strconv.FormatFloat(line.Variance, 'f', 2, 64),
+                variancePercent,
+            }
+
+            if err := csvWriter.Write(record); err != nil {
+                http.Error(w, "Could not write CSV record", http.StatusInternalServerError)
+                return
+            }
+        }
+    }
+}
\ No newline at end of file
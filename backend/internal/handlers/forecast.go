@@ -0,0 +1,52 @@
+package handlers
+
+import (
+    "encoding/json"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+const (
+    defaultForecastDays = 90
+    maxForecastDays     = 365
+)
+
+type ForecastHandler struct{}
+
+func NewForecastHandler() *ForecastHandler {
+    return &ForecastHandler{}
+}
+
+// CashFlow возвращает прогноз остатка на ?days= дней вперед (по умолчанию 90)
+func (h *ForecastHandler) CashFlow(w http.ResponseWriter, r *http.Request) {
+    days := defaultForecastDays
+    if value := r.URL.Query().Get("days"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 || parsed > maxForecastDays {
+            http.Error(w, "Days must be between 1 and 365", http.StatusBadRequest)
+            return
+        }
+        days = parsed
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    loc, ok := userLocation(w, userID)
+    if !ok {
+        return
+    }
+
+    forecast, err := models.GetCashFlowForecast(userID, time.Now().In(loc), days)
+    if err != nil {
+        log.Printf("Error getting cash flow forecast: %v", err)
+        http.Error(w, "Could not get forecast", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(forecast)
+}
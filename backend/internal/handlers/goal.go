@@ -0,0 +1,189 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/gorilla/mux"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+type GoalHandler struct{}
+
+type CreateGoalRequest struct {
+    Name         string     `json:"name"`
+    TargetAmount float64    `json:"target_amount"`
+    Deadline     *time.Time `json:"deadline,omitempty"`
+    CategoryID   *uint      `json:"category_id,omitempty"`
+}
+
+type AddContributionRequest struct {
+    Amount        float64   `json:"amount"`
+    Date          time.Time `json:"date"`
+    TransactionID *uint     `json:"transaction_id,omitempty"`
+}
+
+func NewGoalHandler() *GoalHandler {
+    return &GoalHandler{}
+}
+
+func (h *GoalHandler) Create(w http.ResponseWriter, r *http.Request) {
+    var req CreateGoalRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" {
+        http.Error(w, "Name is required", http.StatusBadRequest)
+        return
+    }
+    if req.TargetAmount <= 0 {
+        http.Error(w, "Target amount must be positive", http.StatusBadRequest)
+        return
+    }
+    if req.Deadline != nil && !req.Deadline.After(time.Now()) {
+        http.Error(w, "Deadline must be in the future", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if req.CategoryID != nil {
+        _, err := models.GetCategory(*req.CategoryID, userID)
+        if errors.Is(err, models.ErrNotFound) {
+            http.Error(w, "Category not found", http.StatusNotFound)
+            return
+        }
+        if err != nil {
+            http.Error(w, "Could not get category", http.StatusInternalServerError)
+            return
+        }
+    }
+
+    goal, err := models.CreateGoal(userID, req.Name, req.TargetAmount, req.Deadline, req.CategoryID)
+    if err != nil {
+        log.Printf("Error creating goal: %v", err)
+        http.Error(w, "Could not create goal", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(goal)
+}
+
+func (h *GoalHandler) List(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    goals, err := models.GetUserGoals(userID, time.Now())
+    if err != nil {
+        log.Printf("Error getting goals: %v", err)
+        http.Error(w, "Could not get goals", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(goals)
+}
+
+func (h *GoalHandler) Get(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    goalID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    goal, err := models.GetGoal(uint(goalID), userID, time.Now())
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Goal not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        log.Printf("Error getting goal: %v", err)
+        http.Error(w, "Could not get goal", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(goal)
+}
+
+func (h *GoalHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    goalID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err = models.DeleteGoal(uint(goalID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Goal not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not delete goal", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
+
+// AddContribution записывает взнос в цель: сумму вручную или существующую транзакцию
+func (h *GoalHandler) AddContribution(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    goalID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+        return
+    }
+
+    var req AddContributionRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if req.TransactionID == nil && req.Amount == 0 {
+        http.Error(w, "Amount or transaction ID is required", http.StatusBadRequest)
+        return
+    }
+    if req.Amount < 0 {
+        http.Error(w, "Amount must be positive", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    contribution, err := models.AddGoalContribution(r.Context(), uint(goalID), userID, req.TransactionID, req.Amount, req.Date)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Goal or transaction not found", http.StatusNotFound)
+        return
+    }
+    if errors.Is(err, models.ErrAlreadyExists) {
+        http.Error(w, "Transaction is already counted toward this goal", http.StatusConflict)
+        return
+    }
+    if err != nil {
+        log.Printf("Error adding goal contribution: %v", err)
+        http.Error(w, "Could not add contribution", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(contribution)
+}
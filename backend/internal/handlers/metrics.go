@@ -0,0 +1,70 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "log"
+    "net/http"
+    "time"
+)
+
+type MetricsHandler struct{}
+
+func NewMetricsHandler() *MetricsHandler {
+    return &MetricsHandler{}
+}
+
+// Get возвращает показатели финансового здоровья за окно ?start_date=&end_date= (2006-01-02).
+// По умолчанию - последние 12 финансовых месяцев, включая текущий
+func (h *MetricsHandler) Get(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return
+    }
+
+    query := r.URL.Query()
+    now := time.Now().In(settings.Location())
+
+    endDate := now
+    if value := query.Get("end_date"); value != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+        if err != nil {
+            http.Error(w, "Invalid end date", http.StatusBadRequest)
+            return
+        }
+        endDate = parsed.AddDate(0, 0, 1).Add(-time.Microsecond)
+    }
+
+    month, _ := models.PeriodBounds(models.PeriodMonthly, settings.MonthStartDay, endDate)
+    startDate := month.AddDate(0, -11, 0)
+    if value := query.Get("start_date"); value != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+        if err != nil {
+            http.Error(w, "Invalid start date", http.StatusBadRequest)
+            return
+        }
+        startDate = parsed
+    }
+    if startDate.After(endDate) {
+        http.Error(w, "Start date must be before end date", http.StatusBadRequest)
+        return
+    }
+
+    metrics, err := models.GetFinancialMetrics(userID, startDate, endDate, settings.MonthStartDay)
+    if errors.Is(err, models.ErrRangeTooLarge) {
+        http.Error(w, "Range must not exceed 10 years", http.StatusBadRequest)
+        return
+    }
+    if err != nil {
+        log.Printf("Error getting financial metrics: %v", err)
+        http.Error(w, "Could not get metrics", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(metrics)
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/gorilla/mux"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+type NetWorthHandler struct{}
+
+type CreateNetWorthItemRequest struct {
+    Name  string    `json:"name"`
+    Type  string    `json:"type"`
+    Kind  string    `json:"kind"`
+    Value float64   `json:"value"`
+    Date  time.Time `json:"date"`
+}
+
+type AddValuationRequest struct {
+    Value float64   `json:"value"`
+    Date  time.Time `json:"date"`
+}
+
+func NewNetWorthHandler() *NetWorthHandler {
+    return &NetWorthHandler{}
+}
+
+func (h *NetWorthHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
+    var req CreateNetWorthItemRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" {
+        http.Error(w, "Name is required", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidNetWorthType(req.Type) {
+        http.Error(w, "Type must be one of asset, liability", http.StatusBadRequest)
+        return
+    }
+    if req.Kind == "" {
+        req.Kind = models.NetWorthKindOther
+    }
+    if !models.ValidNetWorthKind(req.Kind) {
+        http.Error(w, "Kind must be one of account, property, investment, loan, credit_card, other", http.StatusBadRequest)
+        return
+    }
+    if req.Value < 0 {
+        http.Error(w, "Value must not be negative", http.StatusBadRequest)
+        return
+    }
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    // Оценка без даты относится к текущему дню пользователя
+    if req.Date.IsZero() {
+        loc, ok := userLocation(w, userID)
+        if !ok {
+            return
+        }
+        req.Date = time.Now().In(loc)
+    }
+
+    item, err := models.CreateNetWorthItem(r.Context(), userID, req.Name, req.Type, req.Kind, req.Value, req.Date)
+    if err != nil {
+        log.Printf("Error creating net worth item: %v", err)
+        http.Error(w, "Could not create item", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(item)
+}
+
+func (h *NetWorthHandler) ListItems(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    loc, ok := userLocation(w, userID)
+    if !ok {
+        return
+    }
+
+    items, err := models.GetNetWorthItems(userID, time.Now().In(loc))
+    if err != nil {
+        log.Printf("Error getting net worth items: %v", err)
+        http.Error(w, "Could not get items", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(items)
+}
+
+func (h *NetWorthHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    itemID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid item ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err = models.DeleteNetWorthItem(uint(itemID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Item not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not delete item", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
+
+func (h *NetWorthHandler) AddValuation(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    itemID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid item ID", http.StatusBadRequest)
+        return
+    }
+
+    var req AddValuationRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if req.Value < 0 {
+        http.Error(w, "Value must not be negative", http.StatusBadRequest)
+        return
+    }
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if req.Date.IsZero() {
+        loc, ok := userLocation(w, userID)
+        if !ok {
+            return
+        }
+        req.Date = time.Now().In(loc)
+    }
+
+    valuation, err := models.AddValuation(r.Context(), uint(itemID), userID, req.Value, req.Date)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Item not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        log.Printf("Error adding valuation: %v", err)
+        http.Error(w, "Could not add valuation", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(valuation)
+}
+
+func (h *NetWorthHandler) Valuations(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    itemID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid item ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    valuations, err := models.GetValuations(uint(itemID), userID)
+    if err != nil {
+        http.Error(w, "Could not get valuations", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(valuations)
+}
+
+func (h *NetWorthHandler) Current(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    loc, ok := userLocation(w, userID)
+    if !ok {
+        return
+    }
+
+    netWorth, err := models.GetNetWorth(userID, time.Now().In(loc))
+    if err != nil {
+        log.Printf("Error getting net worth: %v", err)
+        http.Error(w, "Could not get net worth", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(netWorth)
+}
+
+// History возвращает чистую стоимость за период ?start_date=&end_date= (2006-01-02)
+// с гранулярностью ?granularity= (по умолчанию month)
+func (h *NetWorthHandler) History(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return
+    }
+
+    query := r.URL.Query()
+    now := time.Now().In(settings.Location())
+
+    endDate := now
+    if value := query.Get("end_date"); value != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+        if err != nil {
+            http.Error(w, "Invalid end date", http.StatusBadRequest)
+            return
+        }
+        endDate = parsed.AddDate(0, 0, 1).Add(-time.Microsecond)
+    }
+
+    startDate := endDate.AddDate(-1, 0, 0)
+    if value := query.Get("start_date"); value != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+        if err != nil {
+            http.Error(w, "Invalid start date", http.StatusBadRequest)
+            return
+        }
+        startDate = parsed
+    }
+    if startDate.After(endDate) {
+        http.Error(w, "Start date must be before end date", http.StatusBadRequest)
+        return
+    }
+
+    granularity := query.Get("granularity")
+    if granularity == "" {
+        granularity = models.GranularityMonth
+    }
+    if !models.ValidGranularity(granularity) {
+        http.Error(w, "Granularity must be one of day, week, month, quarter, year", http.StatusBadRequest)
+        return
+    }
+
+    history, err := models.GetNetWorthHistory(userID, startDate, endDate, granularity, settings.MonthStartDay, now)
+    if errors.Is(err, models.ErrRangeTooLarge) {
+        http.Error(w, "Range is too large for this granularity", http.StatusBadRequest)
+        return
+    }
+    if err != nil {
+        log.Printf("Error getting net worth history: %v", err)
+        http.Error(w, "Could not get net worth history", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(history)
+}
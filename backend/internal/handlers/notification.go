@@ -4,8 +4,10 @@ import (
     "encoding/json"
     "net/http"
     "strconv"
+    "time"
     "github.com/gorilla/mux"
-    "todo-app/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "finance/internal/models"
 )
 
 type NotificationHandler struct{}
@@ -15,7 +17,9 @@ func NewNotificationHandler() *NotificationHandler {
 }
 
 func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
-    userID := getUserIDFromToken(r)
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
     notifications, err := models.GetUserNotifications(userID)
     if err != nil {
         http.Error(w, "Could not get notifications", http.StatusInternalServerError)
@@ -48,4 +52,16 @@ func (h *NotificationHandler) CheckDueTasks(w http.ResponseWriter, r *http.Reque
         return
     }
     w.WriteHeader(http.StatusOK)
-} 
\ No newline at end of file
+}
+
+func (h *NotificationHandler) CheckBudgets(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err := models.CheckUserBudgetAlerts(r.Context(), userID, time.Now())
+    if err != nil {
+        http.Error(w, "Could not check budgets", http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
\ No newline at end of file
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/gorilla/mux"
+    "strconv"
+    "time"
+    "log"
+)
+
+type RecurringHandler struct{}
+
+type CreateRecurringRequest struct {
+    CategoryID  *uint     `json:"category_id,omitempty"`
+    Amount      float64   `json:"amount"`
+    Type        string    `json:"type"`
+    Description string    `json:"description"`
+    Period      string    `json:"period"`
+    StartDate   time.Time `json:"start_date"`
+}
+
+func NewRecurringHandler() *RecurringHandler {
+    return &RecurringHandler{}
+}
+
+func (h *RecurringHandler) Create(w http.ResponseWriter, r *http.Request) {
+    var req CreateRecurringRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if req.Amount <= 0 {
+        http.Error(w, "Amount must be positive", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidTransactionType(req.Type) {
+        http.Error(w, "Type must be one of income, expense", http.StatusBadRequest)
+        return
+    }
+    if !models.ValidPeriod(req.Period) {
+        http.Error(w, "Period must be one of weekly, monthly, quarterly, yearly", http.StatusBadRequest)
+        return
+    }
+    if req.StartDate.IsZero() {
+        http.Error(w, "Start date is required", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    if !validateTransactionCategory(w, userID, req.CategoryID, req.Type) {
+        return
+    }
+
+    recurring, err := models.CreateRecurringTransaction(userID, req.CategoryID, req.Amount, req.Type, req.Description, req.Period, req.StartDate)
+    if err != nil {
+        log.Printf("Error creating recurring transaction: %v", err)
+        http.Error(w, "Could not create recurring transaction", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(recurring)
+}
+
+func (h *RecurringHandler) List(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    recurring, err := models.GetUserRecurringTransactions(userID)
+    if err != nil {
+        http.Error(w, "Could not get recurring transactions", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(recurring)
+}
+
+func (h *RecurringHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    recurringID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        http.Error(w, "Invalid recurring transaction ID", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err = models.DeleteRecurringTransaction(uint(recurringID), userID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Recurring transaction not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, "Could not delete recurring transaction", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
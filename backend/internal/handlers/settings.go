@@ -0,0 +1,152 @@
+package handlers
+
+import (
+    "encoding/json"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "log"
+    "net/http"
+    "time"
+)
+
+type SettingsHandler struct{}
+
+type SetTimezoneRequest struct {
+    Timezone string `json:"timezone"`
+}
+
+type SetMonthStartRequest struct {
+    MonthStartDay int `json:"month_start_day"`
+}
+
+func NewSettingsHandler() *SettingsHandler {
+    return &SettingsHandler{}
+}
+
+// userLocation пишет ошибку в ответ, если не удалось получить часовой пояс пользователя
+func userLocation(w http.ResponseWriter, userID uint) (*time.Location, bool) {
+    loc, err := models.UserLocation(userID)
+    if err != nil {
+        http.Error(w, "Could not get timezone", http.StatusInternalServerError)
+        return nil, false
+    }
+    return loc, true
+}
+
+// userSettings пишет ошибку в ответ, если не удалось получить настройки пользователя
+func userSettings(w http.ResponseWriter, userID uint) (*models.UserSettings, bool) {
+    settings, err := models.GetUserSettings(userID)
+    if err != nil {
+        http.Error(w, "Could not get settings", http.StatusInternalServerError)
+        return nil, false
+    }
+    return settings, true
+}
+
+// userPeriodStartDay возвращает день начала периода по умолчанию: для недель - понедельник,
+// для остальных периодов - день начала финансового месяца пользователя
+func userPeriodStartDay(w http.ResponseWriter, userID uint, period string) (int, bool) {
+    if period == models.PeriodWeekly {
+        return 1, true
+    }
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return 0, false
+    }
+    return settings.MonthStartDay, true
+}
+
+// allowPeriodChange пишет 409 в ответ, если у пользователя включен режим конвертов.
+// Конверты хранятся по началам финансовых месяцев, и после смены часового пояса
+// или дня начала месяца они перестали бы совпадать с новыми периодами
+func allowPeriodChange(w http.ResponseWriter, userID uint) bool {
+    mode, err := models.GetUserBudgetMode(userID)
+    if err != nil {
+        http.Error(w, "Could not get budget mode", http.StatusInternalServerError)
+        return false
+    }
+    if mode == models.BudgetModeEnvelope {
+        http.Error(w, "Time zone and month start day cannot be changed while envelope budgeting is enabled", http.StatusConflict)
+        return false
+    }
+    return true
+}
+
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return
+    }
+
+    json.NewEncoder(w).Encode(settings)
+}
+
+// SetTimezone задает часовой пояс IANA, в котором считается статистика пользователя
+func (h *SettingsHandler) SetTimezone(w http.ResponseWriter, r *http.Request) {
+    var req SetTimezoneRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if !models.ValidTimezone(req.Timezone) {
+        http.Error(w, "Timezone must be an IANA time zone name", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return
+    }
+    if settings.Timezone != req.Timezone && !allowPeriodChange(w, userID) {
+        return
+    }
+
+    if err := models.SetUserTimezone(r.Context(), userID, req.Timezone); err != nil {
+        log.Printf("Error setting timezone: %v", err)
+        http.Error(w, "Could not set timezone", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(req)
+}
+
+// SetMonthStart задает день начала финансового месяца (например, день зарплаты).
+// От него считаются месячные бюджеты, конверты, ряды статистики, сравнения и отчеты
+func (h *SettingsHandler) SetMonthStart(w http.ResponseWriter, r *http.Request) {
+    var req SetMonthStartRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    if !models.ValidStartDay(models.PeriodMonthly, req.MonthStartDay) {
+        http.Error(w, "Month start day must be between 1 and 28", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return
+    }
+    if settings.MonthStartDay != req.MonthStartDay && !allowPeriodChange(w, userID) {
+        return
+    }
+
+    if err := models.SetUserMonthStart(userID, req.MonthStartDay); err != nil {
+        log.Printf("Error setting month start day: %v", err)
+        http.Error(w, "Could not set month start day", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(req)
+}
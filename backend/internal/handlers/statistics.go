@@ -2,6 +2,7 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
     "net/http"
     "finance/internal/models"
     "github.com/golang-jwt/jwt/v5"
@@ -14,12 +15,22 @@ type StatisticsRequest struct {
     StartDate time.Time `json:"start_date"`
     EndDate   time.Time `json:"end_date"`
     Type      string    `json:"type,omitempty"`
+    // Родительская категория для детализации, по умолчанию - корневые категории
+    ParentID *uint `json:"parent_id,omitempty"`
+    // Интервал рядов: day, week, month, quarter, year. Без него ряды дневные
+    // и без заполнения пропусков
+    Granularity string `json:"granularity,omitempty"`
+    // Первый день недели для week: 1 - понедельник (по умолчанию), 7 - воскресенье
+    WeekStart int `json:"week_start,omitempty"`
+    // Сравнить с предыдущим периодом и тем же периодом год назад
+    Compare bool `json:"compare,omitempty"`
 }
 
 type StatisticsResponse struct {
     CategoryTotals []models.CategoryTotal `json:"category_totals,omitempty"`
     DailyTotals   []models.DailyTotal   `json:"daily_totals,omitempty"`
     BalanceHistory []models.DailyTotal   `json:"balance_history,omitempty"`
+    Comparison     *models.StatisticsComparison `json:"comparison,omitempty"`
 }
 
 func NewStatisticsHandler() *StatisticsHandler {
@@ -33,21 +44,53 @@ func (h *StatisticsHandler) GetStatistics(w http.ResponseWriter, r *http.Request
         return
     }
 
+    if req.Granularity != "" && !models.ValidGranularity(req.Granularity) {
+        http.Error(w, "Granularity must be one of day, week, month, quarter, year", http.StatusBadRequest)
+        return
+    }
+    if req.WeekStart == 0 {
+        req.WeekStart = 1
+    }
+    if req.WeekStart < 1 || req.WeekStart > 7 {
+        http.Error(w, "Week start must be between 1 and 7", http.StatusBadRequest)
+        return
+    }
+
     claims := r.Context().Value("claims").(jwt.MapClaims)
     userID := uint(claims["user_id"].(float64))
 
+    // Дни, недели и месяцы статистики считаются в часовом поясе пользователя,
+    // месяцы - с его дня начала финансового месяца
+    settings, ok := userSettings(w, userID)
+    if !ok {
+        return
+    }
+    loc := settings.Location()
+    req.StartDate = req.StartDate.In(loc)
+    req.EndDate = req.EndDate.In(loc)
+
     var response StatisticsResponse
     var err error
 
     // Получаем статистику по категориям
-    response.CategoryTotals, err = models.GetCategoryTotals(userID, req.StartDate, req.EndDate)
+    response.CategoryTotals, err = models.GetCategoryTotals(userID, req.StartDate, req.EndDate, req.ParentID)
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Category not found", http.StatusNotFound)
+        return
+    }
     if err != nil {
         http.Error(w, "Could not get category statistics", http.StatusInternalServerError)
         return
     }
 
     // Если указан тип транзакции, получаем ежедневную статистику
-    if req.Type != "" {
+    if req.Type != "" && req.Granularity != "" {
+        response.DailyTotals, err = models.GetBucketedTotals(userID, req.StartDate, req.EndDate, req.Type, req.Granularity, req.WeekStart, settings.MonthStartDay)
+        if err != nil {
+            http.Error(w, "Could not get daily statistics", http.StatusInternalServerError)
+            return
+        }
+    } else if req.Type != "" {
         response.DailyTotals, err = models.GetDailyTotals(userID, req.StartDate, req.EndDate, req.Type)
         if err != nil {
             http.Error(w, "Could not get daily statistics", http.StatusInternalServerError)
@@ -56,11 +99,32 @@ func (h *StatisticsHandler) GetStatistics(w http.ResponseWriter, r *http.Request
     }
 
     // Получаем историю баланса
-    response.BalanceHistory, err = models.GetBalanceHistory(userID, req.StartDate, req.EndDate)
+    if req.Granularity != "" {
+        response.BalanceHistory, err = models.GetBucketedBalance(userID, req.StartDate, req.EndDate, req.Granularity, req.WeekStart, settings.MonthStartDay)
+    } else {
+        response.BalanceHistory, err = models.GetBalanceHistory(userID, req.StartDate, req.EndDate)
+    }
     if err != nil {
         http.Error(w, "Could not get balance history", http.StatusInternalServerError)
         return
     }
 
+    // Сравнение ряда по типу транзакции, по умолчанию - по расходам
+    if req.Compare {
+        compareType := req.Type
+        if compareType == "" {
+            compareType = models.TransactionTypeExpense
+        }
+        granularity := req.Granularity
+        if granularity == "" {
+            granularity = models.GranularityDay
+        }
+        response.Comparison, err = models.GetStatisticsComparison(userID, req.StartDate, req.EndDate, req.ParentID, compareType, granularity, req.WeekStart, settings.MonthStartDay)
+        if err != nil {
+            http.Error(w, "Could not get comparison", http.StatusInternalServerError)
+            return
+        }
+    }
+
     json.NewEncoder(w).Encode(response)
 } 
\ No newline at end of file
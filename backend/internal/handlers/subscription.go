@@ -0,0 +1,87 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "finance/internal/models"
+    "github.com/golang-jwt/jwt/v5"
+    "log"
+    "net/http"
+    "time"
+)
+
+type SubscriptionHandler struct{}
+
+type SubscriptionKeyRequest struct {
+    Key string `json:"key"`
+}
+
+func NewSubscriptionHandler() *SubscriptionHandler {
+    return &SubscriptionHandler{}
+}
+
+// List возвращает регулярные списания, найденные в истории транзакций
+func (h *SubscriptionHandler) List(w http.ResponseWriter, r *http.Request) {
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    subscriptions, err := models.DetectSubscriptions(userID, time.Now())
+    if err != nil {
+        log.Printf("Error detecting subscriptions: %v", err)
+        http.Error(w, "Could not detect subscriptions", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(subscriptions)
+}
+
+// Confirm превращает найденную подписку в регулярный платеж
+func (h *SubscriptionHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+    var req SubscriptionKeyRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Key == "" {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    recurring, err := models.ConfirmSubscription(userID, req.Key, time.Now())
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Subscription not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        log.Printf("Error confirming subscription: %v", err)
+        http.Error(w, "Could not confirm subscription", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(recurring)
+}
+
+// Dismiss скрывает найденную подписку, чтобы она больше не предлагалась
+func (h *SubscriptionHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+    var req SubscriptionKeyRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Key == "" {
+        http.Error(w, "Invalid request", http.StatusBadRequest)
+        return
+    }
+
+    claims := r.Context().Value("claims").(jwt.MapClaims)
+    userID := uint(claims["user_id"].(float64))
+
+    err := models.DismissSubscription(userID, req.Key, time.Now())
+    if errors.Is(err, models.ErrNotFound) {
+        http.Error(w, "Subscription not found", http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        log.Printf("Error dismissing subscription: %v", err)
+        http.Error(w, "Could not dismiss subscription", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
@@ -1,14 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"finance/internal/models"
 	"github.com/golang-jwt/jwt/v5"
+	"log"
 	"net/http"
 	"time"
 )
 
-type TransactionHandler struct{}
+// Проверки аномалий после создания транзакций выполняются одним фоновым обработчиком
+// из очереди ограниченного размера. Если очередь заполнена, проверка пропускается:
+// ее выполнит ежечасная проверка всех пользователей
+const (
+	anomalyQueueSize    = 100
+	anomalyCheckTimeout = 30 * time.Second
+)
+
+type TransactionHandler struct {
+	anomalyChecks chan uint
+}
 
 type CreateTransactionRequest struct {
 	Amount      float64 `json:"amount"`
@@ -18,7 +31,19 @@ type CreateTransactionRequest struct {
 }
 
 func NewTransactionHandler() *TransactionHandler {
-	return &TransactionHandler{}
+	h := &TransactionHandler{anomalyChecks: make(chan uint, anomalyQueueSize)}
+	go h.checkAnomalies()
+	return h
+}
+
+func (h *TransactionHandler) checkAnomalies() {
+	for userID := range h.anomalyChecks {
+		ctx, cancel := context.WithTimeout(context.Background(), anomalyCheckTimeout)
+		if err := models.CheckUserAnomalies(ctx, userID, time.Now()); err != nil {
+			log.Printf("Error checking spending anomalies for user %d: %v", userID, err)
+		}
+		cancel()
+	}
 }
 
 func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -28,30 +53,36 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Amount <= 0 {
+		http.Error(w, "Amount must be positive", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidTransactionType(req.Type) {
+		http.Error(w, "Type must be one of income, expense", http.StatusBadRequest)
+		return
+	}
+
 	claims := r.Context().Value("claims").(jwt.MapClaims)
 	userID := uint(claims["user_id"].(float64))
 
-	transaction, err := models.CreateTransaction(userID, req.CategoryID, req.Amount, req.Type, req.Description)
+	if !validateTransactionCategory(w, userID, req.CategoryID, req.Type) {
+		return
+	}
+
+	transaction, err := models.CreateTransaction(r.Context(), userID, req.CategoryID, req.Amount, req.Type, req.Description)
 	if err != nil {
 		http.Error(w, "Could not create transaction", http.StatusInternalServerError)
 		return
 	}
 
-	// Обновляем бюджеты, если транзакция относится к категории расходов
-	if req.Type == "expense" && req.CategoryID != nil {
-		budgets, err := models.GetActiveBudgetsForCategory(userID, *req.CategoryID, time.Now())
-		if err != nil {
-			http.Error(w, "Could not get budgets", http.StatusInternalServerError)
-			return
-		}
-
-		for _, budget := range budgets {
-			newSpent := budget.Spent + req.Amount
-			if err := models.UpdateBudgetSpent(budget.ID, newSpent); err != nil {
-				http.Error(w, "Could not update budget", http.StatusInternalServerError)
-				return
-			}
-		}
+	if err := models.CheckUserBudgetAlerts(r.Context(), userID, time.Now()); err != nil {
+		log.Printf("Error checking budget alerts: %v", err)
+	}
+	// Поиск аномалий читает историю за несколько месяцев, поэтому не задерживает ответ
+	select {
+	case h.anomalyChecks <- userID:
+	default:
+		log.Printf("Anomaly check queue is full, skipping check for user %d", userID)
 	}
 
 	json.NewEncoder(w).Encode(transaction)
@@ -68,4 +99,28 @@ func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	json.NewEncoder(w).Encode(transactions)
+}
+
+// validateTransactionCategory проверяет, что категория (если указана) принадлежит
+// пользователю и ее тип совпадает с типом транзакции
+func validateTransactionCategory(w http.ResponseWriter, userID uint, categoryID *uint, transactionType string) bool {
+	if categoryID == nil {
+		return true
+	}
+
+	category, err := models.GetCategory(*categoryID, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Could not get category", http.StatusInternalServerError)
+		return false
+	}
+
+	if category.Type != transactionType {
+		http.Error(w, "Category type does not match transaction type", http.StatusBadRequest)
+		return false
+	}
+	return true
 } 
\ No newline at end of file
@@ -0,0 +1,280 @@
+package models
+
+import (
+    "context"
+    "errors"
+    "finance/internal/db"
+    "fmt"
+    "math"
+    "sort"
+    "time"
+)
+
+const (
+    AnomalyTransaction = "transaction"
+    AnomalyCategory    = "category"
+)
+
+const (
+    // История, с которой сравнивается отдельная транзакция
+    anomalyHistoryDays = 180
+    anomalyMinSamples  = 5
+    // Порог модифицированного z-score по медианному отклонению (Iglewicz, Hoaglin)
+    anomalyMADThreshold = 3.5
+    // Недельные расходы категории сравниваются с предыдущими неделями
+    anomalyWeeks      = 12
+    anomalyMinWeeks   = 4
+    anomalyZThreshold = 2.5
+    // Уведомления создаются только по аномалиям последней недели
+    anomalyNotifyDays = 7
+)
+
+// Anomaly - необычно крупная транзакция или необычно высокие недельные
+// расходы категории. Expected - медиана транзакций или средние недельные расходы
+type Anomaly struct {
+    Kind          string    `json:"kind"`
+    CategoryID    uint      `json:"category_id"`
+    CategoryName  string    `json:"category_name"`
+    TransactionID *uint     `json:"transaction_id,omitempty"`
+    Date          time.Time `json:"date"`
+    Amount        float64   `json:"amount"`
+    Expected      float64   `json:"expected"`
+    Score         float64   `json:"score"`
+    Message       string    `json:"message"`
+
+    alertKey string
+}
+
+func median(values []float64) float64 {
+    sorted := append([]float64(nil), values...)
+    sort.Float64s(sorted)
+    n := len(sorted)
+    if n%2 == 1 {
+        return sorted[n/2]
+    }
+    return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// robustScore возвращает модифицированный z-score значения x относительно выборки.
+// Если медианное отклонение нулевое, используется среднее абсолютное отклонение
+func robustScore(x float64, sample []float64) (float64, float64, bool) {
+    m := median(sample)
+    deviations := make([]float64, len(sample))
+    var meanDeviation float64
+    for i, v := range sample {
+        deviations[i] = math.Abs(v - m)
+        meanDeviation += deviations[i]
+    }
+    meanDeviation /= float64(len(sample))
+
+    if mad := median(deviations); mad > 0 {
+        return 0.6745 * (x - m) / mad, m, true
+    }
+    if meanDeviation > 0 {
+        return (x - m) / (1.253314 * meanDeviation), m, true
+    }
+    return 0, m, false
+}
+
+// DetectAnomalies ищет аномалии расходов за последние days дней
+func DetectAnomalies(userID uint, now time.Time, days int) ([]Anomaly, error) {
+    from := now.AddDate(0, 0, -days)
+    transactions, err := GetUserTransactionsInRange(userID, from.AddDate(0, 0, -anomalyHistoryDays), now)
+    if err != nil {
+        return nil, err
+    }
+
+    categories, err := GetUserCategories(userID)
+    if err != nil {
+        return nil, err
+    }
+    categoryNames := make(map[uint]string)
+    for _, c := range categories {
+        categoryNames[c.ID] = c.Name
+    }
+
+    byCategory := make(map[uint][]Transaction)
+    for _, t := range transactions {
+        if t.Type != TransactionTypeExpense || t.CategoryID == nil {
+            continue
+        }
+        byCategory[*t.CategoryID] = append(byCategory[*t.CategoryID], t)
+    }
+
+    var anomalies []Anomaly
+    for categoryID, list := range byCategory {
+        sort.Slice(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+        name := categoryNames[categoryID]
+
+        anomalies = append(anomalies, transactionAnomalies(list, categoryID, name, from)...)
+        anomalies = append(anomalies, categoryAnomalies(list, categoryID, name, from, now)...)
+    }
+
+    sort.Slice(anomalies, func(i, j int) bool { return anomalies[i].Date.After(anomalies[j].Date) })
+    return anomalies, nil
+}
+
+// transactionAnomalies сравнивает каждую транзакцию начиная с from
+// с транзакциями категории за предыдущие полгода
+func transactionAnomalies(list []Transaction, categoryID uint, name string, from time.Time) []Anomaly {
+    var anomalies []Anomaly
+    for i, t := range list {
+        if t.Date.Before(from) {
+            continue
+        }
+
+        historyStart := t.Date.AddDate(0, 0, -anomalyHistoryDays)
+        var sample []float64
+        for _, h := range list[:i] {
+            if !h.Date.Before(historyStart) {
+                sample = append(sample, h.Amount)
+            }
+        }
+        if len(sample) < anomalyMinSamples {
+            continue
+        }
+
+        score, expected, ok := robustScore(t.Amount, sample)
+        if !ok || score < anomalyMADThreshold {
+            continue
+        }
+
+        id := t.ID
+        anomalies = append(anomalies, Anomaly{
+            Kind:          AnomalyTransaction,
+            CategoryID:    categoryID,
+            CategoryName:  name,
+            TransactionID: &id,
+            Date:          t.Date,
+            Amount:        t.Amount,
+            Expected:      expected,
+            Score:         score,
+            Message:       fmt.Sprintf("Необычно крупная трата в категории «%s»: %.2f при обычных %.2f", name, t.Amount, expected),
+            alertKey:      fmt.Sprintf("anomaly:transaction:%d", t.ID),
+        })
+    }
+    return anomalies
+}
+
+// categoryAnomalies сравнивает расходы категории за каждую неделю начиная с from
+// со средними за предыдущие 12 недель (не раньше первой транзакции категории)
+func categoryAnomalies(list []Transaction, categoryID uint, name string, from, now time.Time) []Anomaly {
+    if len(list) == 0 {
+        return nil
+    }
+
+    // Недели считаются в зоне запроса, чтобы ключи совпадали
+    loc := from.Location()
+    weekly := make(map[time.Time]float64)
+    for _, t := range list {
+        week, _ := PeriodBounds(PeriodWeekly, 1, t.Date.In(loc))
+        weekly[week] += t.Amount
+    }
+    firstWeek, _ := PeriodBounds(PeriodWeekly, 1, list[0].Date.In(loc))
+
+    var anomalies []Anomaly
+    week, weekEnd := PeriodBounds(PeriodWeekly, 1, from)
+    for !week.After(now) {
+        var sample []float64
+        for i := 1; i <= anomalyWeeks; i++ {
+            previous := week.AddDate(0, 0, -7*i)
+            if previous.Before(firstWeek) {
+                break
+            }
+            sample = append(sample, weekly[previous])
+        }
+
+        current := weekly[week]
+        if len(sample) >= anomalyMinWeeks && current > 0 {
+            var mean, variance float64
+            for _, v := range sample {
+                mean += v
+            }
+            mean /= float64(len(sample))
+            for _, v := range sample {
+                variance += (v - mean) * (v - mean)
+            }
+            deviation := math.Sqrt(variance / float64(len(sample)))
+
+            if deviation > 0 {
+                score := (current - mean) / deviation
+                if score >= anomalyZThreshold {
+                    anomalies = append(anomalies, Anomaly{
+                        Kind:         AnomalyCategory,
+                        CategoryID:   categoryID,
+                        CategoryName: name,
+                        Date:         week,
+                        Amount:       current,
+                        Expected:     mean,
+                        Score:        score,
+                        Message: fmt.Sprintf("Расходы в категории «%s» за неделю с %s необычно высоки: %.2f при среднем %.2f",
+                            name, week.Format("02.01.2006"), current, mean),
+                        alertKey: fmt.Sprintf("anomaly:category:%d:%s", categoryID, week.Format("2006-01-02")),
+                    })
+                }
+            }
+        }
+
+        week, weekEnd = PeriodBounds(PeriodWeekly, 1, weekEnd.Add(time.Microsecond))
+    }
+    return anomalies
+}
+
+// CheckUserAnomalies создает уведомления по аномалиям пользователя за последнюю неделю.
+// Каждая аномалия уведомляет один раз. Недели считаются в часовом поясе пользователя
+func CheckUserAnomalies(ctx context.Context, userID uint, now time.Time) error {
+    loc, err := UserLocation(userID)
+    if err != nil {
+        return err
+    }
+
+    anomalies, err := DetectAnomalies(userID, now.In(loc), anomalyNotifyDays)
+    if err != nil {
+        return err
+    }
+    // Поиск читает историю без контекста, поэтому отмену проверяем после него
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    for _, a := range anomalies {
+        if err := CreateAlertNotification(ctx, userID, a.alertKey, a.Message); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// CheckAnomalies проверяет всех пользователей с расходами за последнюю неделю
+func CheckAnomalies(ctx context.Context, now time.Time) error {
+    rows, err := db.DB.QueryContext(ctx,
+        "SELECT DISTINCT user_id FROM transactions WHERE type = 'expense' AND date >= $1",
+        now.AddDate(0, 0, -anomalyNotifyDays),
+    )
+    if err != nil {
+        return err
+    }
+
+    var users []uint
+    for rows.Next() {
+        var userID uint
+        if err := rows.Scan(&userID); err != nil {
+            rows.Close()
+            return err
+        }
+        users = append(users, userID)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    // Ошибка у одного пользователя не должна оставлять остальных без проверки
+    var errs []error
+    for _, userID := range users {
+        if err := CheckUserAnomalies(ctx, userID, now); err != nil {
+            errs = append(errs, fmt.Errorf("user %d: %w", userID, err))
+        }
+    }
+    return errors.Join(errs...)
+}
@@ -1,46 +1,82 @@
 package models
 
 import (
+    "context"
+    "database/sql"
     "finance/internal/db"
+    "github.com/lib/pq"
     "time"
 )
 
 type Budget struct {
-    ID         uint      `json:"id"`
-    UserID     uint      `json:"user_id"`
-    CategoryID uint      `json:"category_id"`
-    Amount     float64   `json:"amount"`
-    Spent      float64   `json:"spent"`
-    StartDate  time.Time `json:"start_date"`
-    EndDate    time.Time `json:"end_date"`
+    ID              uint      `json:"id"`
+    UserID          uint      `json:"user_id"`
+    CategoryID      uint      `json:"category_id"`
+    CategoryIDs     []int64   `json:"category_ids"`
+    TemplateID      *uint     `json:"template_id,omitempty"`
+    Amount          float64   `json:"amount"`
+    Rollover        float64   `json:"rollover"`
+    Spent           float64   `json:"spent"`
+    StartDate       time.Time `json:"start_date"`
+    EndDate         time.Time `json:"end_date"`
+    AlertThresholds []int64   `json:"alert_thresholds"`
 }
 
-func CreateBudget(userID, categoryID uint, amount float64, startDate, endDate time.Time) (*Budget, error) {
-    var id uint
-    err := db.DB.QueryRow(
-        "INSERT INTO budgets (user_id, category_id, amount, spent, start_date, end_date) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-        userID, categoryID, amount, 0, startDate, endDate,
-    ).Scan(&id)
+// budgetDirectCategories - условие "c.id - категория, указанная в бюджете b":
+// основная категория и дополнительные из budget_categories
+var budgetDirectCategories = `(c.id = b.category_id OR c.id IN (
+    SELECT bc.category_id FROM budget_categories bc WHERE bc.budget_id = b.id))`
+
+// budgetCategoryFilter возвращает условие "колонка column - категория бюджета b".
+// Бюджет покрывает указанные в нем категории вместе со всеми подкатегориями
+func budgetCategoryFilter(column string) string {
+    return column + ` IN (
+        WITH RECURSIVE covered AS (
+            SELECT c.id FROM categories c WHERE ` + budgetDirectCategories + `
+            UNION
+            SELECT c.id FROM categories c JOIN covered ON c.parent_id = covered.id
+        )
+        SELECT id FROM covered)`
+}
+
+// Потраченная сумма считается по транзакциям категорий за период бюджета,
+// колонка budgets.spent хранит лишь кэш этого значения
+var budgetSpentQuery = `
+    COALESCE((
+        SELECT SUM(t.amount)
+        FROM transactions t
+        WHERE t.user_id = b.user_id
+        AND ` + budgetCategoryFilter("t.category_id") + `
+        AND t.type = 'expense'
+        AND t.date BETWEEN b.start_date AND b.end_date
+    ), 0)`
+
+var budgetColumns = `b.id, b.user_id, b.category_id,
+    ARRAY[b.category_id] || ARRAY(SELECT bc.category_id FROM budget_categories bc WHERE bc.budget_id = b.id ORDER BY bc.category_id),
+    b.template_id, b.amount, b.rollover, ` + budgetSpentQuery + `, b.start_date, b.end_date, b.alert_thresholds`
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// scanBudget читает колонки budgetColumns, а затем дополнительные колонки в extra
+func scanBudget(row rowScanner, extra ...interface{}) (*Budget, error) {
+    var b Budget
+    var templateID sql.NullInt64
+    dest := []interface{}{&b.ID, &b.UserID, &b.CategoryID, pq.Array(&b.CategoryIDs), &templateID, &b.Amount, &b.Rollover, &b.Spent, &b.StartDate, &b.EndDate, pq.Array(&b.AlertThresholds)}
+    err := row.Scan(append(dest, extra...)...)
     if err != nil {
         return nil, err
     }
-
-    return &Budget{
-        ID:         id,
-        UserID:     userID,
-        CategoryID: categoryID,
-        Amount:     amount,
-        Spent:      0,
-        StartDate:  startDate,
-        EndDate:    endDate,
-    }, nil
+    if templateID.Valid {
+        id := uint(templateID.Int64)
+        b.TemplateID = &id
+    }
+    return &b, nil
 }
 
-func GetUserBudgets(userID uint) ([]Budget, error) {
-    rows, err := db.DB.Query(
-        "SELECT id, user_id, category_id, amount, spent, start_date, end_date FROM budgets WHERE user_id = $1",
-        userID,
-    )
+func queryBudgets(ctx context.Context, q db.Querier, query string, args ...interface{}) ([]Budget, error) {
+    rows, err := q.QueryContext(ctx, query, args...)
     if err != nil {
         return nil, err
     }
@@ -48,52 +84,217 @@ func GetUserBudgets(userID uint) ([]Budget, error) {
 
     var budgets []Budget
     for rows.Next() {
-        var b Budget
-        err := rows.Scan(&b.ID, &b.UserID, &b.CategoryID, &b.Amount, &b.Spent, &b.StartDate, &b.EndDate)
+        b, err := scanBudget(rows)
         if err != nil {
             return nil, err
         }
-        budgets = append(budgets, b)
+        budgets = append(budgets, *b)
     }
-    return budgets, nil
+    return budgets, rows.Err()
 }
 
-func GetActiveBudgetsForCategory(userID, categoryID uint, date time.Time) ([]Budget, error) {
-    rows, err := db.DB.Query(
-        `SELECT id, user_id, category_id, amount, spent, start_date, end_date 
-         FROM budgets 
-         WHERE user_id = $1 
-         AND category_id = $2 
-         AND start_date <= $3 
-         AND end_date >= $3`,
-        userID, categoryID, date,
-    )
+// CreateBudget создает бюджет на набор категорий, первая из них становится основной
+func CreateBudget(ctx context.Context, userID uint, categoryIDs []uint, amount float64, startDate, endDate time.Time) (*Budget, error) {
+    var budget *Budget
+    err := db.WithTx(ctx, func(tx db.Querier) error {
+        var err error
+        budget, err = insertBudget(ctx, tx, userID, categoryIDs, nil, amount, 0, startDate, endDate)
+        return err
+    })
     if err != nil {
         return nil, err
     }
-    defer rows.Close()
+    return budget, nil
+}
 
-    var budgets []Budget
-    for rows.Next() {
-        var b Budget
-        err := rows.Scan(&b.ID, &b.UserID, &b.CategoryID, &b.Amount, &b.Spent, &b.StartDate, &b.EndDate)
+func insertBudget(ctx context.Context, q db.Querier, userID uint, categoryIDs []uint, templateID *uint, amount, rollover float64, startDate, endDate time.Time) (*Budget, error) {
+    var id uint
+    err := q.QueryRowContext(ctx,
+        "INSERT INTO budgets (user_id, category_id, template_id, amount, rollover, spent, start_date, end_date) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+        userID, categoryIDs[0], templateID, amount, rollover, 0, startDate, endDate,
+    ).Scan(&id)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := setBudgetExtraCategories(ctx, q, id, categoryIDs); err != nil {
+        return nil, err
+    }
+
+    // Учитываем транзакции, созданные до бюджета
+    return refreshBudgetSpent(ctx, q, id)
+}
+
+// setBudgetExtraCategories заменяет дополнительные категории бюджета на categoryIDs[1:]
+func setBudgetExtraCategories(ctx context.Context, q db.Querier, budgetID uint, categoryIDs []uint) error {
+    _, err := q.ExecContext(ctx, "DELETE FROM budget_categories WHERE budget_id = $1", budgetID)
+    if err != nil {
+        return err
+    }
+
+    for _, categoryID := range categoryIDs[1:] {
+        if categoryID == categoryIDs[0] {
+            continue
+        }
+        _, err := q.ExecContext(ctx,
+            "INSERT INTO budget_categories (budget_id, category_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+            budgetID, categoryID,
+        )
         if err != nil {
-            return nil, err
+            return err
         }
-        budgets = append(budgets, b)
     }
-    return budgets, nil
+    return nil
+}
+
+func refreshBudgetSpent(ctx context.Context, q db.Querier, budgetID uint) (*Budget, error) {
+    return scanBudget(q.QueryRowContext(ctx,
+        "UPDATE budgets b SET spent = "+budgetSpentQuery+" WHERE b.id = $1 RETURNING "+budgetColumns,
+        budgetID,
+    ))
 }
 
-func UpdateBudgetSpent(budgetID uint, spent float64) error {
-    _, err := db.DB.Exec(
-        "UPDATE budgets SET spent = $1 WHERE id = $2",
-        spent, budgetID,
+func GetUserBudgets(userID uint) ([]Budget, error) {
+    return queryBudgets(context.Background(), db.DB,
+        "SELECT "+budgetColumns+" FROM budgets b WHERE b.user_id = $1 AND NOT b.envelope",
+        userID,
+    )
+}
+
+func GetBudget(id, userID uint) (*Budget, error) {
+    b, err := scanBudget(db.DB.QueryRow(
+        "SELECT "+budgetColumns+" FROM budgets b WHERE b.id = $1 AND b.user_id = $2 AND NOT b.envelope",
+        id, userID,
+    ))
+    if err == sql.ErrNoRows {
+        return nil, ErrNotFound
+    }
+    return b, err
+}
+
+// GetBudgetHistory возвращает все периоды бюджетов, покрывающих категорию,
+// от последнего к первому. Конверты (budgets.envelope) ведутся отдельно и здесь не учитываются
+func GetBudgetHistory(userID, categoryID uint) ([]Budget, error) {
+    return queryBudgets(context.Background(), db.DB,
+        `SELECT `+budgetColumns+`
+         FROM budgets b
+         WHERE b.user_id = $1
+         AND NOT b.envelope
+         AND `+budgetCategoryFilter("$2")+`
+         ORDER BY b.start_date DESC`,
+        userID, categoryID,
+    )
+}
+
+func UpdateBudget(ctx context.Context, id, userID uint, categoryIDs []uint, amount float64, startDate, endDate time.Time) (*Budget, error) {
+    var budget *Budget
+    err := db.WithTx(ctx, func(tx db.Querier) error {
+        var budgetID uint
+        err := tx.QueryRowContext(ctx,
+            `UPDATE budgets
+             SET category_id = $1, amount = $2, start_date = $3, end_date = $4
+             WHERE id = $5 AND user_id = $6 AND NOT envelope
+             RETURNING id`,
+            categoryIDs[0], amount, startDate, endDate, id, userID,
+        ).Scan(&budgetID)
+        if err == sql.ErrNoRows {
+            return ErrNotFound
+        }
+        if err != nil {
+            return err
+        }
+
+        if err := setBudgetExtraCategories(ctx, tx, budgetID, categoryIDs); err != nil {
+            return err
+        }
+
+        // Категории или период могли измениться, поэтому пересчитываем кэш
+        budget, err = refreshBudgetSpent(ctx, tx, budgetID)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return budget, nil
+}
+
+func SetBudgetAlertThresholds(id, userID uint, thresholds []int64) error {
+    result, err := db.DB.Exec(
+        "UPDATE budgets SET alert_thresholds = $1 WHERE id = $2 AND user_id = $3 AND NOT envelope",
+        pq.Array(thresholds), id, userID,
+    )
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
+}
+
+func GetActiveBudgetsForCategory(userID, categoryID uint, date time.Time) ([]Budget, error) {
+    return queryBudgets(context.Background(), db.DB,
+        `SELECT `+budgetColumns+`
+         FROM budgets b
+         WHERE b.user_id = $1
+         AND NOT b.envelope
+         AND `+budgetCategoryFilter("$2")+`
+         AND b.start_date <= $3
+         AND b.end_date >= $3`,
+        userID, categoryID, date,
     )
-    return err
 }
 
-func DeleteBudget(budgetID uint) error {
-    _, err := db.DB.Exec("DELETE FROM budgets WHERE id = $1", budgetID)
+// AddBudgetSpent атомарно увеличивает spent у всех бюджетов, покрывающих категорию и
+// активных на дату транзакции
+func AddBudgetSpent(ctx context.Context, q db.Querier, userID, categoryID uint, amount float64, date time.Time) error {
+    _, err := q.ExecContext(ctx,
+        `UPDATE budgets b
+         SET spent = spent + $1
+         WHERE b.user_id = $2
+         AND `+budgetCategoryFilter("$3")+`
+         AND b.start_date <= $4
+         AND b.end_date >= $4`,
+        amount, userID, categoryID, date,
+    )
     return err
-} 
\ No newline at end of file
+}
+
+// RecalculateBudgets пересчитывает кэш spent у всех бюджетов пользователя
+// (или всех пользователей, если userID = nil) и возвращает количество обновленных бюджетов
+func RecalculateBudgets(ctx context.Context, userID *uint) (int64, error) {
+    result, err := db.DB.ExecContext(ctx,
+        "UPDATE budgets b SET spent = "+budgetSpentQuery+" WHERE $1::integer IS NULL OR b.user_id = $1",
+        userID,
+    )
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
+
+func DeleteBudget(id, userID uint) error {
+    // Конверты удаляются только вместе с категорией, чтобы не сломать перенос остатков
+    result, err := db.DB.Exec("DELETE FROM budgets WHERE id = $1 AND user_id = $2 AND NOT envelope", id, userID)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
+}
\ No newline at end of file
@@ -0,0 +1,112 @@
+package models
+
+import (
+    "context"
+    "errors"
+    "finance/internal/db"
+    "fmt"
+    "time"
+)
+
+// Прогноз перерасхода строится только после того, как прошла эта доля периода,
+// иначе одна крупная покупка в первый день дает ложную тревогу
+const minProjectionElapsed = 0.2
+
+// CheckBudgetAlerts создает уведомления по всем активным бюджетам
+func CheckBudgetAlerts(ctx context.Context, now time.Time) error {
+    return checkBudgetAlerts(ctx, 0, now)
+}
+
+// CheckUserBudgetAlerts создает уведомления по активным бюджетам пользователя
+func CheckUserBudgetAlerts(ctx context.Context, userID uint, now time.Time) error {
+    return checkBudgetAlerts(ctx, userID, now)
+}
+
+func checkBudgetAlerts(ctx context.Context, userID uint, now time.Time) error {
+    rows, err := db.DB.QueryContext(ctx,
+        `SELECT `+budgetColumns+`,
+            (SELECT string_agg(c.name, ', ' ORDER BY c.name) FROM categories c WHERE `+budgetDirectCategories+`)
+         FROM budgets b
+         WHERE ($1 = 0 OR b.user_id = $1)
+         AND NOT b.envelope
+         AND b.start_date <= $2
+         AND b.end_date >= $2`,
+        userID, now,
+    )
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    type budgetAlert struct {
+        budget   *Budget
+        category string
+    }
+
+    var active []budgetAlert
+    for rows.Next() {
+        var category string
+        b, err := scanBudget(rows, &category)
+        if err != nil {
+            return err
+        }
+        active = append(active, budgetAlert{budget: b, category: category})
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    rows.Close()
+
+    var errs []error
+    for _, a := range active {
+        if err := notifyBudget(ctx, a.budget, a.category, now); err != nil {
+            errs = append(errs, fmt.Errorf("budget %d: %w", a.budget.ID, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// notifyBudget создает по одному уведомлению на каждый пройденный порог
+// и одно уведомление о прогнозируемом перерасходе
+func notifyBudget(ctx context.Context, b *Budget, category string, now time.Time) error {
+    limit := b.Amount + b.Rollover
+    if limit <= 0 {
+        return nil
+    }
+
+    percent := b.Spent / limit * 100
+    for _, threshold := range b.AlertThresholds {
+        if percent < float64(threshold) {
+            continue
+        }
+
+        message := fmt.Sprintf("Бюджет «%s» израсходован на %d%%: потрачено %.2f из %.2f", category, threshold, b.Spent, limit)
+        if threshold >= 100 {
+            message = fmt.Sprintf("Бюджет «%s» исчерпан: потрачено %.2f из %.2f", category, b.Spent, limit)
+        }
+
+        err := CreateBudgetNotification(ctx, b.UserID, b.ID, fmt.Sprintf("threshold:%d", threshold), message)
+        if err != nil {
+            return err
+        }
+    }
+
+    if b.Spent >= limit {
+        return nil
+    }
+
+    total := b.EndDate.Sub(b.StartDate)
+    elapsed := now.Sub(b.StartDate)
+    if total <= 0 || float64(elapsed) < float64(total)*minProjectionElapsed {
+        return nil
+    }
+
+    projected := b.Spent / float64(elapsed) * float64(total)
+    if projected <= limit {
+        return nil
+    }
+
+    message := fmt.Sprintf("При текущем темпе бюджет «%s» будет превышен до %s: прогноз %.2f из %.2f",
+        category, b.EndDate.Format("02.01.2006"), projected, limit)
+    return CreateBudgetNotification(ctx, b.UserID, b.ID, "projected", message)
+}
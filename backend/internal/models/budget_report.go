@@ -0,0 +1,207 @@
+package models
+
+import (
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Строка отчета - набор категорий одного бюджета или отдельная категория без бюджета.
+// Variance положительна, если потрачено меньше запланированного
+type BudgetReportLine struct {
+    CategoryIDs     []uint   `json:"category_ids"`
+    CategoryName    string   `json:"category_name"`
+    Budgeted        float64  `json:"budgeted"`
+    Actual          float64  `json:"actual"`
+    Variance        float64  `json:"variance"`
+    VariancePercent *float64 `json:"variance_percent"`
+}
+
+type BudgetReportPeriod struct {
+    StartDate time.Time          `json:"start_date"`
+    EndDate   time.Time          `json:"end_date"`
+    Lines     []BudgetReportLine `json:"lines"`
+}
+
+// ReportPeriod переводит гранулярность отчета в период бюджета
+func ReportPeriod(granularity string) (string, bool) {
+    switch granularity {
+    case "month":
+        return PeriodMonthly, true
+    case "quarter":
+        return PeriodQuarterly, true
+    case "year":
+        return PeriodYearly, true
+    }
+    return "", false
+}
+
+func categorySetKey(ids []uint) string {
+    parts := make([]string, len(ids))
+    for i, id := range ids {
+        parts[i] = strconv.FormatUint(uint64(id), 10)
+    }
+    return strings.Join(parts, ",")
+}
+
+// overlap возвращает длительность пересечения двух промежутков
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+    start, end := aStart, aEnd
+    if bStart.After(start) {
+        start = bStart
+    }
+    if bEnd.Before(end) {
+        end = bEnd
+    }
+    if end.Before(start) {
+        return 0
+    }
+    return end.Sub(start)
+}
+
+// GetBudgetReport сравнивает запланированные и фактические расходы по периодам.
+// Сумма бюджета вместе с перенесенным остатком распределяется по периодам
+// пропорционально пересечению дат
+func GetBudgetReport(userID uint, startDate, endDate time.Time, period string, monthStart int) ([]BudgetReportPeriod, error) {
+    budgets, err := GetUserBudgets(userID)
+    if err != nil {
+        return nil, err
+    }
+
+    categories, err := GetUserCategories(userID)
+    if err != nil {
+        return nil, err
+    }
+    categoryNames := make(map[uint]string)
+    for _, c := range categories {
+        categoryNames[c.ID] = c.Name
+    }
+
+    transactions, err := GetUserTransactionsInRange(userID, startDate, endDate)
+    if err != nil {
+        return nil, err
+    }
+
+    // Каждая категория относится ровно к одной строке, чтобы факт не задваивался
+    lineIDs := make(map[string][]uint)
+    budgetLine := make([]string, len(budgets))
+    for i, b := range budgets {
+        ids := make([]uint, len(b.CategoryIDs))
+        for j, id := range b.CategoryIDs {
+            ids[j] = uint(id)
+        }
+        sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+        key := categorySetKey(ids)
+        lineIDs[key] = ids
+        budgetLine[i] = key
+    }
+    // Если категория входит в несколько бюджетов, она относится к самому узкому из них
+    // (с наименьшим числом категорий), при равенстве - к набору с меньшими id
+    lines := make([]string, 0, len(lineIDs))
+    for key := range lineIDs {
+        lines = append(lines, key)
+    }
+    sort.Slice(lines, func(i, j int) bool {
+        a, b := lineIDs[lines[i]], lineIDs[lines[j]]
+        if len(a) != len(b) {
+            return len(a) < len(b)
+        }
+        for k := range a {
+            if a[k] != b[k] {
+                return a[k] < b[k]
+            }
+        }
+        return false
+    })
+    categoryLine := make(map[uint]string)
+    for _, key := range lines {
+        for _, id := range lineIDs[key] {
+            if _, ok := categoryLine[id]; !ok {
+                categoryLine[id] = key
+            }
+        }
+    }
+    // Подкатегории без собственного бюджета учитываются в строке бюджета предка
+    for _, key := range lines {
+        for id := range CategoryDescendants(categories, lineIDs[key]) {
+            if _, ok := categoryLine[id]; !ok {
+                categoryLine[id] = key
+            }
+        }
+    }
+    lineFor := func(categoryID uint) string {
+        if key, ok := categoryLine[categoryID]; ok {
+            return key
+        }
+        key := categorySetKey([]uint{categoryID})
+        lineIDs[key] = []uint{categoryID}
+        categoryLine[categoryID] = key
+        return key
+    }
+
+    var report []BudgetReportPeriod
+    periodStart, periodEnd := PeriodBounds(period, monthStart, startDate)
+    for !periodStart.After(endDate) {
+        from, to := periodStart, periodEnd
+        if from.Before(startDate) {
+            from = startDate
+        }
+        if to.After(endDate) {
+            to = endDate
+        }
+
+        budgeted := make(map[string]float64)
+        actual := make(map[string]float64)
+
+        for i, b := range budgets {
+            duration := b.EndDate.Sub(b.StartDate)
+            if duration <= 0 {
+                continue
+            }
+            share := overlap(b.StartDate, b.EndDate, from, to)
+            if share > 0 {
+                budgeted[budgetLine[i]] += (b.Amount + b.Rollover) * float64(share) / float64(duration)
+            }
+        }
+
+        for _, t := range transactions {
+            if t.Type != "expense" || t.CategoryID == nil || t.Date.Before(from) || t.Date.After(to) {
+                continue
+            }
+            actual[lineFor(*t.CategoryID)] += t.Amount
+        }
+
+        p := BudgetReportPeriod{StartDate: from, EndDate: to}
+        for key, ids := range lineIDs {
+            if budgeted[key] == 0 && actual[key] == 0 {
+                continue
+            }
+
+            names := make([]string, 0, len(ids))
+            for _, id := range ids {
+                names = append(names, categoryNames[id])
+            }
+
+            line := BudgetReportLine{
+                CategoryIDs:  ids,
+                CategoryName: strings.Join(names, ", "),
+                Budgeted:     budgeted[key],
+                Actual:       actual[key],
+                Variance:     budgeted[key] - actual[key],
+            }
+            if line.Budgeted != 0 {
+                percent := line.Variance / line.Budgeted * 100
+                line.VariancePercent = &percent
+            }
+            p.Lines = append(p.Lines, line)
+        }
+        sort.Slice(p.Lines, func(i, j int) bool { return p.Lines[i].CategoryName < p.Lines[j].CategoryName })
+
+        report = append(report, p)
+        periodStart, periodEnd = PeriodBounds(period, monthStart, periodEnd.Add(time.Microsecond))
+    }
+
+    return report, nil
+}
\ No newline at end of file
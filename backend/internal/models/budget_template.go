@@ -0,0 +1,240 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "finance/internal/db"
+    "fmt"
+    "time"
+)
+
+const (
+    PeriodWeekly    = "weekly"
+    PeriodMonthly   = "monthly"
+    PeriodQuarterly = "quarterly"
+    PeriodYearly    = "yearly"
+)
+
+// Что переносится в следующий период: ничего, только остаток
+// или остаток вместе с перерасходом
+const (
+    RolloverNone    = "none"
+    RolloverUnspent = "unspent"
+    RolloverAll     = "all"
+)
+
+type BudgetTemplate struct {
+    ID           uint    `json:"id"`
+    UserID       uint    `json:"user_id"`
+    CategoryID   uint    `json:"category_id"`
+    Amount       float64 `json:"amount"`
+    Period       string  `json:"period"`
+    StartDay     int     `json:"start_day"`
+    RolloverMode string  `json:"rollover_mode"`
+    Active       bool    `json:"active"`
+}
+
+func ValidPeriod(period string) bool {
+    switch period {
+    case PeriodWeekly, PeriodMonthly, PeriodQuarterly, PeriodYearly:
+        return true
+    }
+    return false
+}
+
+func ValidRolloverMode(mode string) bool {
+    switch mode {
+    case RolloverNone, RolloverUnspent, RolloverAll:
+        return true
+    }
+    return false
+}
+
+// ValidStartDay проверяет день начала периода: для недели это день недели
+// (1 - понедельник, 7 - воскресенье), для остальных периодов - число месяца.
+// Число ограничено 28, чтобы период существовал в любом месяце
+func ValidStartDay(period string, startDay int) bool {
+    if period == PeriodWeekly {
+        return startDay >= 1 && startDay <= 7
+    }
+    return startDay >= 1 && startDay <= 28
+}
+
+// PeriodBounds возвращает начало и конец периода, в который попадает date
+func PeriodBounds(period string, startDay int, date time.Time) (time.Time, time.Time) {
+    loc := date.Location()
+
+    if period == PeriodWeekly {
+        weekday := int(date.Weekday())
+        if weekday == 0 {
+            weekday = 7
+        }
+        offset := (weekday - startDay + 7) % 7
+        start := time.Date(date.Year(), date.Month(), date.Day()-offset, 0, 0, 0, 0, loc)
+        return start, start.AddDate(0, 0, 7).Add(-time.Microsecond)
+    }
+
+    months := 1
+    switch period {
+    case PeriodQuarterly:
+        months = 3
+    case PeriodYearly:
+        months = 12
+    }
+
+    start := time.Date(date.Year(), date.Month(), startDay, 0, 0, 0, 0, loc)
+    if date.Before(start) {
+        start = start.AddDate(0, -1, 0)
+    }
+    // Кварталы и годы отсчитываются от января
+    start = start.AddDate(0, -(int(start.Month()-1) % months), 0)
+
+    return start, start.AddDate(0, months, 0).Add(-time.Microsecond)
+}
+
+func CreateBudgetTemplate(userID, categoryID uint, amount float64, period string, startDay int, rolloverMode string) (*BudgetTemplate, error) {
+    var id uint
+    err := db.DB.QueryRow(
+        `INSERT INTO budget_templates (user_id, category_id, amount, period, start_day, rollover_mode, active)
+         VALUES ($1, $2, $3, $4, $5, $6, true) RETURNING id`,
+        userID, categoryID, amount, period, startDay, rolloverMode,
+    ).Scan(&id)
+    if err != nil {
+        return nil, err
+    }
+
+    return &BudgetTemplate{
+        ID:           id,
+        UserID:       userID,
+        CategoryID:   categoryID,
+        Amount:       amount,
+        Period:       period,
+        StartDay:     startDay,
+        RolloverMode: rolloverMode,
+        Active:       true,
+    }, nil
+}
+
+func queryBudgetTemplates(query string, args ...interface{}) ([]BudgetTemplate, error) {
+    rows, err := db.DB.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var templates []BudgetTemplate
+    for rows.Next() {
+        var t BudgetTemplate
+        err := rows.Scan(&t.ID, &t.UserID, &t.CategoryID, &t.Amount, &t.Period, &t.StartDay, &t.RolloverMode, &t.Active)
+        if err != nil {
+            return nil, err
+        }
+        templates = append(templates, t)
+    }
+    return templates, rows.Err()
+}
+
+func GetUserBudgetTemplates(userID uint) ([]BudgetTemplate, error) {
+    return queryBudgetTemplates(
+        `SELECT id, user_id, category_id, amount, period, start_day, rollover_mode, active
+         FROM budget_templates
+         WHERE user_id = $1
+         ORDER BY id`,
+        userID,
+    )
+}
+
+func DeleteBudgetTemplate(id, userID uint) error {
+    // Созданные бюджеты остаются в истории, отвязываясь от шаблона
+    result, err := db.DB.Exec("DELETE FROM budget_templates WHERE id = $1 AND user_id = $2", id, userID)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
+}
+
+// GenerateRecurringBudgets создает бюджеты по активным шаблонам вплоть до
+// периода, в который попадает now, перенося остаток согласно rollover_mode
+func GenerateRecurringBudgets(ctx context.Context, now time.Time) error {
+    templates, err := queryBudgetTemplates(
+        `SELECT id, user_id, category_id, amount, period, start_day, rollover_mode, active
+         FROM budget_templates
+         WHERE active = true`,
+    )
+    if err != nil {
+        return err
+    }
+
+    var errs []error
+    for _, t := range templates {
+        if err := GenerateTemplateBudgets(ctx, t, now); err != nil {
+            errs = append(errs, fmt.Errorf("template %d: %w", t.ID, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// GenerateTemplateBudgets создает недостающие периоды одного шаблона.
+// Границы периодов считаются в часовом поясе владельца шаблона
+func GenerateTemplateBudgets(ctx context.Context, t BudgetTemplate, now time.Time) error {
+    loc, err := UserLocation(t.UserID)
+    if err != nil {
+        return err
+    }
+    now = now.In(loc)
+
+    return db.WithTx(ctx, func(tx db.Querier) error {
+        last, err := scanBudget(tx.QueryRowContext(ctx,
+            `SELECT `+budgetColumns+`
+             FROM budgets b
+             WHERE b.template_id = $1
+             ORDER BY b.start_date DESC
+             LIMIT 1`,
+            t.ID,
+        ))
+        if err == sql.ErrNoRows {
+            start, end := PeriodBounds(t.Period, t.StartDay, now)
+            _, err = insertBudget(ctx, tx, t.UserID, []uint{t.CategoryID}, &t.ID, t.Amount, 0, start, end)
+            return err
+        }
+        if err != nil {
+            return err
+        }
+
+        // Если генерация пропустила несколько периодов, создаем их по очереди,
+        // чтобы перенос остатка шел по цепочке
+        for last.EndDate.Before(now) {
+            start, end := PeriodBounds(t.Period, t.StartDay, last.EndDate.In(loc).Add(time.Microsecond))
+            last, err = insertBudget(ctx, tx, t.UserID, []uint{t.CategoryID}, &t.ID, t.Amount, t.rolloverFrom(last), start, end)
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (t BudgetTemplate) rolloverFrom(prev *Budget) float64 {
+    remainder := prev.Amount + prev.Rollover - prev.Spent
+
+    switch t.RolloverMode {
+    case RolloverUnspent:
+        if remainder > 0 {
+            return remainder
+        }
+    case RolloverAll:
+        return remainder
+    }
+    return 0
+}
\ No newline at end of file
@@ -41,36 +41,66 @@ func CreateCategory(name string, userID uint) (*Category, error) {
         return nil, err
     }
     return &category, nil
+}
 =======
+    "database/sql"
     "finance/internal/db"
 )
 
 type Category struct {
-    ID     uint   `json:"id"`
-    UserID uint   `json:"user_id"`
-    Name   string `json:"name"`
-    Type   string `json:"type"`
+    ID          uint   `json:"id"`
+    UserID      uint   `json:"user_id"`
+    ParentID    *uint  `json:"parent_id"`
+    Name        string `json:"name"`
+    Type        string `json:"type"`
+    Icon        string `json:"icon"`
+    Color       string `json:"color"`
+    SortOrder   int    `json:"sort_order"`
+    Description string `json:"description"`
+    Archived    bool   `json:"archived"`
 }
 
-func CreateCategory(userID uint, name, categoryType string) (*Category, error) {
-    var id uint
+// CreateCategory добавляет категорию в конец списка пользователя
+func CreateCategory(userID uint, name, categoryType string, parentID *uint, icon, color, description string) (*Category, error) {
+    c := Category{
+        UserID:      userID,
+        ParentID:    parentID,
+        Name:        name,
+        Type:        categoryType,
+        Icon:        icon,
+        Color:       color,
+        Description: description,
+    }
     err := db.DB.QueryRow(
-        "INSERT INTO categories (user_id, name, type) VALUES ($1, $2, $3) RETURNING id",
-        userID, name, categoryType,
-    ).Scan(&id)
+        `INSERT INTO categories (user_id, parent_id, name, type, icon, color, description, sort_order)
+         VALUES ($1, $2, $3, $4, $5, $6, $7,
+            (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM categories WHERE user_id = $1))
+         RETURNING id, sort_order`,
+        userID, parentID, name, categoryType, icon, color, description,
+    ).Scan(&c.ID, &c.SortOrder)
     if err != nil {
         return nil, err
     }
 
-    return &Category{
-        ID:     id,
-        UserID: userID,
-        Name:   name,
-        Type:   categoryType,
-    }, nil
->>>>>>> my-feature-branch
+    return &c, nil
 }
 
+func GetCategory(id, userID uint) (*Category, error) {
+    var c Category
+    err := db.DB.QueryRow(
+        "SELECT id, user_id, parent_id, name, type, icon, color, sort_order, description, archived FROM categories WHERE id = $1 AND user_id = $2",
+        id, userID,
+    ).Scan(&c.ID, &c.UserID, &c.ParentID, &c.Name, &c.Type, &c.Icon, &c.Color, &c.SortOrder, &c.Description, &c.Archived)
+    if err == sql.ErrNoRows {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &c, nil
+}
+>>>>>>> my-feature-branch
+
 func GetUserCategories(userID uint) ([]Category, error) {
     rows, err := db.DB.Query(
 <<<<<<< HEAD
@@ -79,7 +109,7 @@ func GetUserCategories(userID uint) ([]Category, error) {
          WHERE user_id = $1 
          ORDER BY created_at DESC`,
 =======
-        "SELECT id, user_id, name, type FROM categories WHERE user_id = $1",
+        "SELECT id, user_id, parent_id, name, type, icon, color, sort_order, description, archived FROM categories WHERE user_id = $1 ORDER BY sort_order, name",
 >>>>>>> my-feature-branch
         userID,
     )
@@ -178,14 +208,15 @@ func UpdateTaskCategory(taskID, categoryID, userID uint) error {
     }
 
     return nil
+}
 =======
         var c Category
-        err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Type)
+        err := rows.Scan(&c.ID, &c.UserID, &c.ParentID, &c.Name, &c.Type, &c.Icon, &c.Color, &c.SortOrder, &c.Description, &c.Archived)
         if err != nil {
             return nil, err
         }
         categories = append(categories, c)
     }
     return categories, nil
+}
 >>>>>>> my-feature-branch
-} 
\ No newline at end of file
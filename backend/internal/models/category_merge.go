@@ -0,0 +1,170 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "finance/internal/db"
+)
+
+// UpdateCategory меняет название, оформление, описание и признак архивной.
+// Архивная категория скрыта из списков выбора, но остается в статистике
+func UpdateCategory(id, userID uint, name, icon, color, description string, archived bool) (*Category, error) {
+    var c Category
+    err := db.DB.QueryRow(
+        `UPDATE categories SET name = $1, icon = $2, color = $3, description = $4, archived = $5
+         WHERE id = $6 AND user_id = $7
+         RETURNING id, user_id, parent_id, name, type, icon, color, sort_order, description, archived`,
+        name, icon, color, description, archived, id, userID,
+    ).Scan(&c.ID, &c.UserID, &c.ParentID, &c.Name, &c.Type, &c.Icon, &c.Color, &c.SortOrder, &c.Description, &c.Archived)
+    if err == sql.ErrNoRows {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &c, nil
+}
+
+// DeleteCategory удаляет категорию. Если reassignTo задан, транзакции, бюджеты и
+// подкатегории переходят в нее, иначе транзакции остаются без категории,
+// подкатегории поднимаются на уровень выше, а бюджеты без других категорий удаляются
+func DeleteCategory(ctx context.Context, id, userID uint, reassignTo *uint) error {
+    return db.WithTx(ctx, func(tx db.Querier) error {
+        if reassignTo != nil {
+            if err := reassignCategory(ctx, tx, userID, id, *reassignTo); err != nil {
+                return err
+            }
+        } else if err := uncategorize(ctx, tx, userID, id); err != nil {
+            return err
+        }
+        return deleteCategoryRow(ctx, tx, userID, id)
+    })
+}
+
+// MergeCategories переносит все данные категории sourceID в targetID и удаляет sourceID
+func MergeCategories(ctx context.Context, userID, sourceID, targetID uint) (*Category, error) {
+    err := db.WithTx(ctx, func(tx db.Querier) error {
+        if err := reassignCategory(ctx, tx, userID, sourceID, targetID); err != nil {
+            return err
+        }
+        return deleteCategoryRow(ctx, tx, userID, sourceID)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return GetCategory(targetID, userID)
+}
+
+// reassignCategory переводит транзакции, регулярные платежи, бюджеты, шаблоны
+// и подкатегории категории fromID в категорию toID
+func reassignCategory(ctx context.Context, tx db.Querier, userID, fromID, toID uint) error {
+    // Нельзя слить категорию в ее потомка - подкатегории образовали бы цикл
+    var descendant bool
+    err := tx.QueryRowContext(ctx,
+        `WITH RECURSIVE ancestors AS (
+            SELECT id, parent_id FROM categories WHERE id = $1
+            UNION
+            SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+        )
+        SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`,
+        toID, fromID,
+    ).Scan(&descendant)
+    if err != nil {
+        return err
+    }
+    if descendant {
+        return ErrCategoryCycle
+    }
+
+    queries := []string{
+        `UPDATE transactions SET category_id = $3 WHERE user_id = $1 AND category_id = $2`,
+        `UPDATE recurring_transactions SET category_id = $3 WHERE user_id = $1 AND category_id = $2`,
+        `UPDATE budget_templates SET category_id = $3 WHERE user_id = $1 AND category_id = $2`,
+        `UPDATE goals SET category_id = $3 WHERE user_id = $1 AND category_id = $2`,
+        `UPDATE categories SET parent_id = $3 WHERE user_id = $1 AND parent_id = $2`,
+        // Конверты одного месяца складываются
+        `INSERT INTO budgets (user_id, category_id, amount, rollover, spent, start_date, end_date, envelope)
+         SELECT user_id, $3, amount, 0, 0, start_date, end_date, true
+         FROM budgets
+         WHERE user_id = $1 AND category_id = $2 AND envelope
+         ON CONFLICT (user_id, category_id, start_date) WHERE envelope
+         DO UPDATE SET amount = budgets.amount + EXCLUDED.amount`,
+        `DELETE FROM budgets WHERE user_id = $1 AND category_id = $2 AND envelope`,
+        `INSERT INTO budget_categories (budget_id, category_id)
+         SELECT bc.budget_id, $3
+         FROM budget_categories bc
+         JOIN budgets b ON b.id = bc.budget_id
+         WHERE b.user_id = $1 AND bc.category_id = $2 AND b.category_id <> $3
+         ON CONFLICT DO NOTHING`,
+        `DELETE FROM budget_categories bc USING budgets b
+         WHERE b.id = bc.budget_id AND b.user_id = $1 AND bc.category_id = $2`,
+        `UPDATE budgets SET category_id = $3 WHERE user_id = $1 AND category_id = $2`,
+        // Категория могла уже быть дополнительной в бюджете, куда стала основной
+        `DELETE FROM budget_categories bc USING budgets b
+         WHERE b.id = bc.budget_id AND b.user_id = $1 AND bc.category_id = b.category_id`,
+    }
+    for _, query := range queries {
+        if _, err := tx.ExecContext(ctx, query, userID, fromID, toID); err != nil {
+            return err
+        }
+    }
+    if err := moveRollups(ctx, tx, userID, fromID, &toID); err != nil {
+        return err
+    }
+    return refreshUserBudgets(ctx, tx, userID)
+}
+
+// uncategorize отвязывает от категории fromID все данные перед ее удалением
+func uncategorize(ctx context.Context, tx db.Querier, userID, fromID uint) error {
+    queries := []string{
+        `UPDATE transactions SET category_id = NULL WHERE user_id = $1 AND category_id = $2`,
+        `UPDATE recurring_transactions SET category_id = NULL WHERE user_id = $1 AND category_id = $2`,
+        `DELETE FROM budget_templates WHERE user_id = $1 AND category_id = $2`,
+        `UPDATE categories
+         SET parent_id = (SELECT parent_id FROM categories WHERE id = $2)
+         WHERE user_id = $1 AND parent_id = $2`,
+        // Основной категорией бюджета становится одна из оставшихся дополнительных
+        `UPDATE budgets b
+         SET category_id = (SELECT MIN(bc.category_id) FROM budget_categories bc
+             WHERE bc.budget_id = b.id AND bc.category_id <> $2)
+         WHERE b.user_id = $1 AND b.category_id = $2
+         AND EXISTS (SELECT 1 FROM budget_categories bc WHERE bc.budget_id = b.id AND bc.category_id <> $2)`,
+        `DELETE FROM budget_categories bc USING budgets b
+         WHERE b.id = bc.budget_id AND b.user_id = $1
+         AND (bc.category_id = $2 OR bc.category_id = b.category_id)`,
+        `DELETE FROM budgets WHERE user_id = $1 AND category_id = $2`,
+    }
+    for _, query := range queries {
+        if _, err := tx.ExecContext(ctx, query, userID, fromID); err != nil {
+            return err
+        }
+    }
+    if err := moveRollups(ctx, tx, userID, fromID, nil); err != nil {
+        return err
+    }
+    return refreshUserBudgets(ctx, tx, userID)
+}
+
+func deleteCategoryRow(ctx context.Context, tx db.Querier, userID, id uint) error {
+    result, err := tx.ExecContext(ctx, "DELETE FROM categories WHERE id = $1 AND user_id = $2", id, userID)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+func refreshUserBudgets(ctx context.Context, q db.Querier, userID uint) error {
+    _, err := q.ExecContext(ctx,
+        "UPDATE budgets b SET spent = "+budgetSpentQuery+" WHERE b.user_id = $1",
+        userID,
+    )
+    return err
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+    "context"
+    "finance/internal/db"
+    "regexp"
+)
+
+const (
+    maxCategoryIconLength        = 50
+    maxCategoryDescriptionLength = 500
+)
+
+var (
+    categoryColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+    categoryIconPattern  = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// ValidCategoryColor - пустая строка или цвет в формате #RRGGBB
+func ValidCategoryColor(color string) bool {
+    return color == "" || categoryColorPattern.MatchString(color)
+}
+
+// ValidCategoryIcon - пустая строка или имя иконки Material Icons (shopping_cart)
+func ValidCategoryIcon(icon string) bool {
+    return icon == "" || (len(icon) <= maxCategoryIconLength && categoryIconPattern.MatchString(icon))
+}
+
+func ValidCategoryDescription(description string) bool {
+    return len([]rune(description)) <= maxCategoryDescriptionLength
+}
+
+// ReorderCategories задает ручной порядок категорий: позиция в categoryIDs
+// становится sort_order. Категории, не вошедшие в список, сохраняют свой порядок
+func ReorderCategories(ctx context.Context, userID uint, categoryIDs []uint) error {
+    return db.WithTx(ctx, func(tx db.Querier) error {
+        for i, id := range categoryIDs {
+            result, err := tx.ExecContext(ctx,
+                "UPDATE categories SET sort_order = $1 WHERE id = $2 AND user_id = $3",
+                i+1, id, userID,
+            )
+            if err != nil {
+                return err
+            }
+
+            rowsAffected, err := result.RowsAffected()
+            if err != nil {
+                return err
+            }
+            if rowsAffected == 0 {
+                return ErrNotFound
+            }
+        }
+        return nil
+    })
+}
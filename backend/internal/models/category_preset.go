@@ -0,0 +1,139 @@
+package models
+
+import (
+    "context"
+    "finance/internal/db"
+    "strings"
+)
+
+const (
+    PresetLocaleRussian = "ru"
+    PresetLocaleEnglish = "en"
+)
+
+// categoryPreset - категория стандартного набора. Icon - имя иконки Material Icons
+type categoryPreset struct {
+    Name  string
+    Type  string
+    Icon  string
+    Color string
+}
+
+var categoryPresets = map[string][]categoryPreset{
+    PresetLocaleRussian: {
+        {"Зарплата", TransactionTypeIncome, "work", "#4CAF50"},
+        {"Подработка", TransactionTypeIncome, "handyman", "#8BC34A"},
+        {"Подарки", TransactionTypeIncome, "card_giftcard", "#CDDC39"},
+        {"Проценты и кэшбэк", TransactionTypeIncome, "savings", "#009688"},
+        {"Продукты", TransactionTypeExpense, "shopping_cart", "#FF9800"},
+        {"Кафе и рестораны", TransactionTypeExpense, "restaurant", "#FF5722"},
+        {"Транспорт", TransactionTypeExpense, "directions_bus", "#2196F3"},
+        {"Жилье и ЖКХ", TransactionTypeExpense, "home", "#795548"},
+        {"Связь и интернет", TransactionTypeExpense, "wifi", "#3F51B5"},
+        {"Здоровье", TransactionTypeExpense, "local_hospital", "#F44336"},
+        {"Одежда", TransactionTypeExpense, "checkroom", "#9C27B0"},
+        {"Развлечения", TransactionTypeExpense, "movie", "#E91E63"},
+        {"Образование", TransactionTypeExpense, "school", "#673AB7"},
+        {"Подписки", TransactionTypeExpense, "subscriptions", "#607D8B"},
+        {"Прочее", TransactionTypeExpense, "more_horiz", "#9E9E9E"},
+    },
+    PresetLocaleEnglish: {
+        {"Salary", TransactionTypeIncome, "work", "#4CAF50"},
+        {"Side income", TransactionTypeIncome, "handyman", "#8BC34A"},
+        {"Gifts", TransactionTypeIncome, "card_giftcard", "#CDDC39"},
+        {"Interest & cashback", TransactionTypeIncome, "savings", "#009688"},
+        {"Groceries", TransactionTypeExpense, "shopping_cart", "#FF9800"},
+        {"Eating out", TransactionTypeExpense, "restaurant", "#FF5722"},
+        {"Transport", TransactionTypeExpense, "directions_bus", "#2196F3"},
+        {"Housing & utilities", TransactionTypeExpense, "home", "#795548"},
+        {"Phone & internet", TransactionTypeExpense, "wifi", "#3F51B5"},
+        {"Health", TransactionTypeExpense, "local_hospital", "#F44336"},
+        {"Clothing", TransactionTypeExpense, "checkroom", "#9C27B0"},
+        {"Entertainment", TransactionTypeExpense, "movie", "#E91E63"},
+        {"Education", TransactionTypeExpense, "school", "#673AB7"},
+        {"Subscriptions", TransactionTypeExpense, "subscriptions", "#607D8B"},
+        {"Other", TransactionTypeExpense, "more_horiz", "#9E9E9E"},
+    },
+}
+
+func ValidPresetLocale(locale string) bool {
+    _, ok := categoryPresets[locale]
+    return ok
+}
+
+// ApplyCategoryPreset добавляет недостающие категории стандартного набора.
+// Категории сравниваются по имени без учета регистра и типу. При reset категориям
+// набора возвращаются иконки и цвета, а остальные категории архивируются -
+// они пропадают из выбора, но их транзакции остаются в статистике
+func ApplyCategoryPreset(ctx context.Context, userID uint, locale string, reset bool) error {
+    presets := categoryPresets[locale]
+
+    return db.WithTx(ctx, func(tx db.Querier) error {
+        rows, err := tx.QueryContext(ctx,
+            "SELECT id, name, type FROM categories WHERE user_id = $1 FOR UPDATE",
+            userID,
+        )
+        if err != nil {
+            return err
+        }
+
+        var existing []Category
+        for rows.Next() {
+            var c Category
+            if err := rows.Scan(&c.ID, &c.Name, &c.Type); err != nil {
+                rows.Close()
+                return err
+            }
+            existing = append(existing, c)
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return err
+        }
+
+        matched := make(map[uint]bool)
+        for i, p := range presets {
+            var found *Category
+            for j := range existing {
+                if existing[j].Type == p.Type && strings.EqualFold(existing[j].Name, p.Name) {
+                    found = &existing[j]
+                    break
+                }
+            }
+
+            if found == nil {
+                _, err := tx.ExecContext(ctx,
+                    "INSERT INTO categories (user_id, name, type, icon, color, sort_order) VALUES ($1, $2, $3, $4, $5, $6)",
+                    userID, p.Name, p.Type, p.Icon, p.Color, i+1,
+                )
+                if err != nil {
+                    return err
+                }
+                continue
+            }
+
+            matched[found.ID] = true
+            if reset {
+                _, err := tx.ExecContext(ctx,
+                    "UPDATE categories SET icon = $1, color = $2, sort_order = $3, archived = FALSE WHERE id = $4",
+                    p.Icon, p.Color, i+1, found.ID,
+                )
+                if err != nil {
+                    return err
+                }
+            }
+        }
+
+        if reset {
+            for _, c := range existing {
+                if matched[c.ID] {
+                    continue
+                }
+                if _, err := tx.ExecContext(ctx, "UPDATE categories SET archived = TRUE WHERE id = $1", c.ID); err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    })
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+    "context"
+    "finance/internal/db"
+)
+
+// MoveCategory переносит категорию под нового родителя (nil - в корень).
+// Нельзя переносить категорию под саму себя или своего потомка
+func MoveCategory(ctx context.Context, id, userID uint, parentID *uint) (*Category, error) {
+    err := db.WithTx(ctx, func(tx db.Querier) error {
+        // Блокируем категории пользователя, чтобы параллельные переносы не создали цикл
+        _, err := tx.ExecContext(ctx, "SELECT id FROM categories WHERE user_id = $1 FOR UPDATE", userID)
+        if err != nil {
+            return err
+        }
+
+        if parentID != nil {
+            var cycle bool
+            err := tx.QueryRowContext(ctx,
+                `WITH RECURSIVE ancestors AS (
+                    SELECT id, parent_id FROM categories WHERE id = $1
+                    UNION
+                    SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+                )
+                SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`,
+                *parentID, id,
+            ).Scan(&cycle)
+            if err != nil {
+                return err
+            }
+            if cycle {
+                return ErrCategoryCycle
+            }
+        }
+
+        result, err := tx.ExecContext(ctx,
+            "UPDATE categories SET parent_id = $1 WHERE id = $2 AND user_id = $3",
+            parentID, id, userID,
+        )
+        if err != nil {
+            return err
+        }
+
+        rowsAffected, err := result.RowsAffected()
+        if err != nil {
+            return err
+        }
+        if rowsAffected == 0 {
+            return ErrNotFound
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return GetCategory(id, userID)
+}
+
+// CategoryDescendants возвращает категории из rootIDs вместе со всеми их потомками
+func CategoryDescendants(categories []Category, rootIDs []uint) map[uint]bool {
+    children := make(map[uint][]uint)
+    for _, c := range categories {
+        if c.ParentID != nil {
+            children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+        }
+    }
+
+    result := make(map[uint]bool)
+    queue := append([]uint(nil), rootIDs...)
+    for len(queue) > 0 {
+        id := queue[0]
+        queue = queue[1:]
+        if result[id] {
+            continue
+        }
+        result[id] = true
+        queue = append(queue, children[id]...)
+    }
+    return result
+}
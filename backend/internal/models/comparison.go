@@ -0,0 +1,145 @@
+package models
+
+import (
+    "sort"
+    "time"
+)
+
+type PeriodRange struct {
+    StartDate time.Time `json:"start_date"`
+    EndDate   time.Time `json:"end_date"`
+}
+
+// ComparisonValue - значение за период сравнения и отличие текущего периода от него.
+// DeltaPercent не задан, если значение периода сравнения нулевое
+type ComparisonValue struct {
+    Value        float64  `json:"value"`
+    Delta        float64  `json:"delta"`
+    DeltaPercent *float64 `json:"delta_percent"`
+}
+
+type CategoryComparison struct {
+    CategoryID   uint            `json:"category_id"`
+    CategoryName string          `json:"category_name"`
+    Type         string          `json:"type"`
+    Color        string          `json:"color"`
+    Current      float64         `json:"current"`
+    Previous     ComparisonValue `json:"previous"`
+    YearAgo      ComparisonValue `json:"year_ago"`
+}
+
+// SeriesComparison - точка ряда текущего периода и точки с тем же номером
+// в предыдущем периоде и год назад
+type SeriesComparison struct {
+    Date     time.Time       `json:"date"`
+    Current  float64         `json:"current"`
+    Previous ComparisonValue `json:"previous"`
+    YearAgo  ComparisonValue `json:"year_ago"`
+}
+
+type StatisticsComparison struct {
+    Current    PeriodRange          `json:"current"`
+    Previous   PeriodRange          `json:"previous"`
+    YearAgo    PeriodRange          `json:"year_ago"`
+    Categories []CategoryComparison `json:"categories"`
+    Series     []SeriesComparison   `json:"series"`
+}
+
+func compareValues(current, compared float64) ComparisonValue {
+    v := ComparisonValue{Value: compared, Delta: current - compared}
+    if compared != 0 {
+        percent := v.Delta / compared * 100
+        v.DeltaPercent = &percent
+    }
+    return v
+}
+
+// PreviousPeriod возвращает период той же длины, предшествующий [startDate, endDate].
+// Для целых финансовых месяцев (с дня monthStart) сдвиг делается на то же число месяцев,
+// чтобы февраль сравнивался с январем целиком
+func PreviousPeriod(startDate, endDate time.Time, monthStart int) (time.Time, time.Time) {
+    if isMonthStart(startDate, monthStart) && isMonthStart(endDate.Add(time.Microsecond), monthStart) {
+        next := endDate.Add(time.Microsecond)
+        months := (next.Year()-startDate.Year())*12 + int(next.Month()-startDate.Month())
+        if months > 0 {
+            return startDate.AddDate(0, -months, 0), startDate.Add(-time.Microsecond)
+        }
+    }
+
+    length := endDate.Sub(startDate)
+    previousEnd := startDate.Add(-time.Microsecond)
+    return previousEnd.Add(-length), previousEnd
+}
+
+func isMonthStart(date time.Time, monthStart int) bool {
+    return date.Day() == monthStart && date.Hour() == 0 && date.Minute() == 0 && date.Second() == 0 && date.Nanosecond() == 0
+}
+
+// GetStatisticsComparison сравнивает суммы категорий уровня parentID и ряд transactionType
+// по интервалам granularity с предыдущим периодом и тем же периодом год назад
+func GetStatisticsComparison(userID uint, startDate, endDate time.Time, parentID *uint, transactionType, granularity string, weekStart, monthStart int) (*StatisticsComparison, error) {
+    previousStart, previousEnd := PreviousPeriod(startDate, endDate, monthStart)
+    comparison := &StatisticsComparison{
+        Current:  PeriodRange{startDate, endDate},
+        Previous: PeriodRange{previousStart, previousEnd},
+        YearAgo:  PeriodRange{startDate.AddDate(-1, 0, 0), endDate.AddDate(-1, 0, 0)},
+    }
+    periods := []PeriodRange{comparison.Current, comparison.Previous, comparison.YearAgo}
+
+    // Суммы категорий по трем периодам, сопоставленные по категории
+    byCategory := make(map[uint]*CategoryComparison)
+    var values [3]map[uint]float64
+    for i, p := range periods {
+        totals, err := GetCategoryTotals(userID, p.StartDate, p.EndDate, parentID)
+        if err != nil {
+            return nil, err
+        }
+        values[i] = make(map[uint]float64)
+        for _, ct := range totals {
+            values[i][ct.CategoryID] = ct.Total
+            if _, ok := byCategory[ct.CategoryID]; !ok {
+                byCategory[ct.CategoryID] = &CategoryComparison{
+                    CategoryID:   ct.CategoryID,
+                    CategoryName: ct.CategoryName,
+                    Type:         ct.Type,
+                    Color:        ct.Color,
+                }
+            }
+        }
+    }
+    for id, c := range byCategory {
+        c.Current = values[0][id]
+        c.Previous = compareValues(c.Current, values[1][id])
+        c.YearAgo = compareValues(c.Current, values[2][id])
+        comparison.Categories = append(comparison.Categories, *c)
+    }
+    sort.Slice(comparison.Categories, func(i, j int) bool {
+        return comparison.Categories[i].Current > comparison.Categories[j].Current
+    })
+
+    // Ряды сопоставляются по номеру интервала от начала периода
+    var series [3][]DailyTotal
+    for i, p := range periods {
+        totals, err := GetBucketedTotals(userID, p.StartDate, p.EndDate, transactionType, granularity, weekStart, monthStart)
+        if err != nil {
+            return nil, err
+        }
+        series[i] = totals
+    }
+    valueAt := func(totals []DailyTotal, i int) float64 {
+        if i < len(totals) {
+            return totals[i].Total
+        }
+        return 0
+    }
+    for i, point := range series[0] {
+        comparison.Series = append(comparison.Series, SeriesComparison{
+            Date:     point.Date,
+            Current:  point.Total,
+            Previous: compareValues(point.Total, valueAt(series[1], i)),
+            YearAgo:  compareValues(point.Total, valueAt(series[2], i)),
+        })
+    }
+
+    return comparison, nil
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "finance/internal/db"
+    "time"
+)
+
+const (
+    BudgetModeStandard = "standard"
+    BudgetModeEnvelope = "envelope"
+)
+
+// Конверт - это бюджет категории расходов на финансовый месяц пользователя с флагом envelope.
+// Сумма бюджета - распределенные в конверт деньги, остаток переходит на следующий месяц
+type Envelope struct {
+    CategoryID   uint    `json:"category_id"`
+    CategoryName string  `json:"category_name"`
+    Assigned     float64 `json:"assigned"`
+    Spent        float64 `json:"spent"`
+    Available    float64 `json:"available"`
+}
+
+type EnvelopeSummary struct {
+    Month             time.Time  `json:"month"`
+    AvailableToAssign float64    `json:"available_to_assign"`
+    Envelopes         []Envelope `json:"envelopes"`
+}
+
+func ValidBudgetMode(mode string) bool {
+    return mode == BudgetModeStandard || mode == BudgetModeEnvelope
+}
+
+// firstEnvelopeMonth возвращает месяц, с которого пользователь ведет конверты.
+// Расходы до него уже оплачены и в конвертах не учитываются
+func firstEnvelopeMonth(userID uint, monthStart time.Time) (time.Time, error) {
+    var first sql.NullTime
+    err := db.DB.QueryRow(
+        "SELECT MIN(start_date) FROM budgets WHERE user_id = $1 AND envelope",
+        userID,
+    ).Scan(&first)
+    if err != nil {
+        return time.Time{}, err
+    }
+    if !first.Valid || first.Time.After(monthStart) {
+        return monthStart, nil
+    }
+    return first.Time, nil
+}
+
+func GetEnvelopeSummary(userID uint, month time.Time, monthStartDay int) (*EnvelopeSummary, error) {
+    monthStart, monthEnd := PeriodBounds(PeriodMonthly, monthStartDay, month)
+    first, err := firstEnvelopeMonth(userID, monthStart)
+    if err != nil {
+        return nil, err
+    }
+
+    summary := &EnvelopeSummary{Month: monthStart}
+
+    // Свободные деньги: все доходы минус уже потраченное до начала ведения конвертов,
+    // расходы без категории и все распределенное по конвертам
+    err = db.DB.QueryRow(
+        `SELECT
+            COALESCE((SELECT SUM(amount) FROM transactions
+                WHERE user_id = $1 AND type = 'income' AND date <= $3), 0)
+            - COALESCE((SELECT SUM(amount) FROM transactions
+                WHERE user_id = $1 AND type = 'expense' AND date < $4), 0)
+            - COALESCE((SELECT SUM(amount) FROM transactions
+                WHERE user_id = $1 AND type = 'expense' AND category_id IS NULL AND date BETWEEN $4 AND $3), 0)
+            - COALESCE((SELECT SUM(amount) FROM budgets
+                WHERE user_id = $1 AND envelope AND start_date <= $2), 0)`,
+        userID, monthStart, monthEnd, first,
+    ).Scan(&summary.AvailableToAssign)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.DB.Query(
+        `SELECT c.id, c.name,
+            COALESCE((SELECT b.amount FROM budgets b
+                WHERE b.user_id = $1 AND b.envelope AND b.category_id = c.id AND b.start_date = $2), 0),
+            COALESCE((SELECT SUM(t.amount) FROM transactions t
+                WHERE t.user_id = $1 AND t.type = 'expense' AND t.category_id = c.id AND t.date BETWEEN $2 AND $3), 0),
+            COALESCE((SELECT SUM(b.amount) FROM budgets b
+                WHERE b.user_id = $1 AND b.envelope AND b.category_id = c.id AND b.start_date <= $2), 0)
+            - COALESCE((SELECT SUM(t.amount) FROM transactions t
+                WHERE t.user_id = $1 AND t.type = 'expense' AND t.category_id = c.id AND t.date BETWEEN $4 AND $3), 0)
+         FROM categories c
+         WHERE c.user_id = $1 AND c.type = 'expense'
+         ORDER BY c.name`,
+        userID, monthStart, monthEnd, first,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var e Envelope
+        err := rows.Scan(&e.CategoryID, &e.CategoryName, &e.Assigned, &e.Spent, &e.Available)
+        if err != nil {
+            return nil, err
+        }
+        summary.Envelopes = append(summary.Envelopes, e)
+    }
+    return summary, rows.Err()
+}
+
+// upsertEnvelope добавляет delta к конверту месяца, создавая его при необходимости.
+// Если replace, сумма конверта заменяется на delta
+func upsertEnvelope(ctx context.Context, q db.Querier, userID, categoryID uint, month time.Time, monthStartDay int, delta float64, replace bool) (*Budget, error) {
+    monthStart, monthEnd := PeriodBounds(PeriodMonthly, monthStartDay, month)
+
+    onConflict := "budgets.amount + EXCLUDED.amount"
+    if replace {
+        onConflict = "EXCLUDED.amount"
+    }
+
+    var id uint
+    err := q.QueryRowContext(ctx,
+        `INSERT INTO budgets (user_id, category_id, amount, rollover, spent, start_date, end_date, envelope)
+         VALUES ($1, $2, $3, 0, 0, $4, $5, true)
+         ON CONFLICT (user_id, category_id, start_date) WHERE envelope
+         DO UPDATE SET amount = `+onConflict+`
+         RETURNING id`,
+        userID, categoryID, delta, monthStart, monthEnd,
+    ).Scan(&id)
+    if err != nil {
+        return nil, err
+    }
+
+    return refreshBudgetSpent(ctx, q, id)
+}
+
+func AssignEnvelope(ctx context.Context, userID, categoryID uint, month time.Time, monthStartDay int, amount float64) (*Budget, error) {
+    return upsertEnvelope(ctx, db.DB, userID, categoryID, month, monthStartDay, amount, true)
+}
+
+// MoveEnvelopeFunds переносит деньги между конвертами одного месяца.
+// Из конверта нельзя забрать больше, чем в него распределено
+func MoveEnvelopeFunds(ctx context.Context, userID, fromCategoryID, toCategoryID uint, month time.Time, monthStartDay int, amount float64) error {
+    monthStart, _ := PeriodBounds(PeriodMonthly, monthStartDay, month)
+
+    return db.WithTx(ctx, func(tx db.Querier) error {
+        result, err := tx.ExecContext(ctx,
+            `UPDATE budgets
+             SET amount = amount - $1
+             WHERE user_id = $2
+             AND category_id = $3
+             AND start_date = $4
+             AND envelope
+             AND amount >= $1`,
+            amount, userID, fromCategoryID, monthStart,
+        )
+        if err != nil {
+            return err
+        }
+
+        rowsAffected, err := result.RowsAffected()
+        if err != nil {
+            return err
+        }
+        if rowsAffected == 0 {
+            return ErrInsufficientFunds
+        }
+
+        _, err = upsertEnvelope(ctx, tx, userID, toCategoryID, month, monthStartDay, amount, false)
+        return err
+    })
+}
\ No newline at end of file
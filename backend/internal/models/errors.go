@@ -3,5 +3,9 @@ package models
 import "errors"
 
 var (
-    ErrNotFound = errors.New("not found")
+    ErrNotFound          = errors.New("not found")
+    ErrInsufficientFunds = errors.New("insufficient funds")
+    ErrCategoryCycle     = errors.New("category cannot be moved under itself or its descendant")
+    ErrAlreadyExists     = errors.New("already exists")
+    ErrRangeTooLarge     = errors.New("range is too large")
 ) 
\ No newline at end of file
@@ -0,0 +1,289 @@
+package models
+
+import (
+    "finance/internal/db"
+    "math"
+    "sort"
+    "time"
+)
+
+const (
+    // forecastLookbackDays - окно, по которому считается средний ежедневный поток
+    forecastLookbackDays = 90
+    // forecastZ - множитель для 90% доверительного интервала
+    forecastZ = 1.645
+    // Пределы сезонного коэффициента, чтобы единичный крупный месяц не искажал прогноз
+    minSeasonalFactor = 0.5
+    maxSeasonalFactor = 2.0
+)
+
+// CategoryBaseline - средний ежедневный нерегулярный поток по категории
+// (без учета регулярных платежей этой категории)
+type CategoryBaseline struct {
+    CategoryID   *uint   `json:"category_id"`
+    CategoryName string  `json:"category_name"`
+    Type         string  `json:"type"`
+    DailyAmount  float64 `json:"daily_amount"`
+}
+
+type ForecastDay struct {
+    Date      time.Time `json:"date"`
+    Scheduled float64   `json:"scheduled"`
+    Baseline  float64   `json:"baseline"`
+    Balance   float64   `json:"balance"`
+    Lower     float64   `json:"lower"`
+    Upper     float64   `json:"upper"`
+}
+
+type CashFlowForecast struct {
+    CurrentBalance float64            `json:"current_balance"`
+    Baselines      []CategoryBaseline `json:"baselines"`
+    Days           []ForecastDay      `json:"days"`
+    // Первая дата, когда прогнозный остаток становится отрицательным
+    FirstNegativeDate *time.Time `json:"first_negative_date"`
+    // Первая дата, когда отрицательной становится нижняя граница интервала
+    FirstRiskDate *time.Time `json:"first_risk_date"`
+}
+
+type baselineKey struct {
+    CategoryID uint
+    Type       string
+}
+
+func signedAmount(transactionType string, amount float64) float64 {
+    if transactionType == TransactionTypeExpense {
+        return -amount
+    }
+    return amount
+}
+
+func dayStart(date time.Time) time.Time {
+    return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+// lookbackDay возвращает номер дня окна для date или -1, если дата вне окна
+func lookbackDay(lookbackStart, date time.Time) int {
+    i := int(date.Sub(lookbackStart).Hours() / 24)
+    if i < 0 || i >= forecastLookbackDays {
+        return -1
+    }
+    return i
+}
+
+// GetCurrentBalance возвращает разницу всех доходов и расходов до момента now
+func GetCurrentBalance(userID uint, now time.Time) (float64, error) {
+    var balance float64
+    err := db.DB.QueryRow(
+        `SELECT COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END), 0)
+         FROM transactions WHERE user_id = $1 AND date <= $2`,
+        userID, now,
+    ).Scan(&balance)
+    return balance, err
+}
+
+// GetCashFlowForecast прогнозирует остаток на days дней вперед: текущий остаток,
+// плюс регулярные платежи по их расписанию, плюс средний нерегулярный поток
+// каждой категории за последние 90 дней с поправкой на сезонность прошлого года.
+// Интервал строится по разбросу дневного нерегулярного потока и растет как корень из числа дней
+func GetCashFlowForecast(userID uint, now time.Time, days int) (*CashFlowForecast, error) {
+    balance, err := GetCurrentBalance(userID, now)
+    if err != nil {
+        return nil, err
+    }
+
+    history, err := GetUserTransactionsInRange(userID, now.AddDate(-1, 0, 0), now)
+    if err != nil {
+        return nil, err
+    }
+
+    recurring, err := GetUserRecurringTransactions(userID)
+    if err != nil {
+        return nil, err
+    }
+
+    categories, err := GetUserCategories(userID)
+    if err != nil {
+        return nil, err
+    }
+    categoryNames := make(map[uint]string)
+    for _, c := range categories {
+        categoryNames[c.ID] = c.Name
+    }
+
+    // Транзакции, совпадающие с регулярным платежом по описанию (например, подтвержденные
+    // подписки), исключаются из нерегулярного потока целиком: их будущие списания уже
+    // идут по расписанию. Для регулярных платежей без таких транзакций из факта
+    // вычитается их расписание
+    type recurringKey struct {
+        Type string
+        Key  string
+    }
+    described := make(map[recurringKey]bool)
+    matched := make(map[recurringKey]bool)
+    for _, rt := range recurring {
+        if key := subscriptionKey(rt.Description); key != "" {
+            described[recurringKey{rt.Type, key}] = true
+        }
+    }
+
+    today := dayStart(now)
+    lookbackStart := today.AddDate(0, 0, -forecastLookbackDays)
+
+    // Нерегулярный поток: факт за окно минус регулярные платежи за то же окно
+    lookbackTotals := make(map[baselineKey]float64)
+    dailyNet := make([]float64, forecastLookbackDays)
+    monthTotals := make(map[baselineKey]map[time.Month]float64)
+    yearTotals := make(map[baselineKey]float64)
+    earliest := now
+
+    for _, t := range history {
+        if t.Date.Before(earliest) {
+            earliest = t.Date
+        }
+        if rk := (recurringKey{t.Type, subscriptionKey(t.Description)}); described[rk] {
+            matched[rk] = true
+            continue
+        }
+
+        key := baselineKey{Type: t.Type}
+        if t.CategoryID != nil {
+            key.CategoryID = *t.CategoryID
+        }
+
+        if monthTotals[key] == nil {
+            monthTotals[key] = make(map[time.Month]float64)
+        }
+        monthTotals[key][t.Date.In(now.Location()).Month()] += t.Amount
+        yearTotals[key] += t.Amount
+
+        if !t.Date.Before(lookbackStart) && t.Date.Before(today) {
+            lookbackTotals[key] += t.Amount
+            if i := lookbackDay(lookbackStart, t.Date); i >= 0 {
+                dailyNet[i] += signedAmount(t.Type, t.Amount)
+            }
+        }
+    }
+
+    // У новых пользователей средние считаются только по дням с начала истории
+    lookbackDays := int(math.Round(today.Sub(dayStart(earliest.In(now.Location()))).Hours() / 24))
+    if lookbackDays > forecastLookbackDays {
+        lookbackDays = forecastLookbackDays
+    }
+    if lookbackDays < 1 {
+        lookbackDays = 1
+    }
+    historyStart := today.AddDate(0, 0, -lookbackDays)
+
+    for _, rt := range recurring {
+        if matched[recurringKey{rt.Type, subscriptionKey(rt.Description)}] {
+            continue
+        }
+        key := baselineKey{Type: rt.Type}
+        if rt.CategoryID != nil {
+            key.CategoryID = *rt.CategoryID
+        }
+        for _, date := range rt.Occurrences(historyStart.Add(-time.Microsecond), today.Add(-time.Microsecond)) {
+            lookbackTotals[key] -= rt.Amount
+            if i := lookbackDay(lookbackStart, date); i >= 0 {
+                dailyNet[i] -= signedAmount(rt.Type, rt.Amount)
+            }
+        }
+    }
+
+    // Сезонность учитывается, только если история покрывает почти весь прошлый год
+    hasYear := !earliest.After(now.AddDate(0, -11, 0))
+    seasonalFactor := func(key baselineKey, month time.Month) float64 {
+        if !hasYear || yearTotals[key] <= 0 {
+            return 1
+        }
+        factor := monthTotals[key][month] / (yearTotals[key] / 12)
+        return math.Max(minSeasonalFactor, math.Min(maxSeasonalFactor, factor))
+    }
+    lookbackMonths := []time.Month{
+        lookbackStart.Month(),
+        lookbackStart.AddDate(0, 0, forecastLookbackDays/2).Month(),
+        today.AddDate(0, 0, -1).Month(),
+    }
+
+    forecast := &CashFlowForecast{CurrentBalance: balance}
+    daily := make(map[baselineKey]float64)
+    lookbackFactor := make(map[baselineKey]float64)
+    for key, total := range lookbackTotals {
+        if total <= 0 {
+            continue
+        }
+        daily[key] = total / float64(lookbackDays)
+
+        var factor float64
+        for _, month := range lookbackMonths {
+            factor += seasonalFactor(key, month)
+        }
+        lookbackFactor[key] = factor / float64(len(lookbackMonths))
+
+        baseline := CategoryBaseline{Type: key.Type, DailyAmount: daily[key]}
+        if key.CategoryID != 0 {
+            id := key.CategoryID
+            baseline.CategoryID = &id
+            baseline.CategoryName = categoryNames[id]
+        }
+        forecast.Baselines = append(forecast.Baselines, baseline)
+    }
+    sort.Slice(forecast.Baselines, func(i, j int) bool {
+        return forecast.Baselines[i].DailyAmount > forecast.Baselines[j].DailyAmount
+    })
+
+    dailyNet = dailyNet[forecastLookbackDays-lookbackDays:]
+    var mean, variance float64
+    for _, v := range dailyNet {
+        mean += v
+    }
+    mean /= float64(lookbackDays)
+    for _, v := range dailyNet {
+        variance += (v - mean) * (v - mean)
+    }
+    deviation := math.Sqrt(variance / float64(lookbackDays))
+
+    // Регулярные платежи по дням прогноза; оставшиеся на сегодня относятся к первому дню
+    horizonEnd := today.AddDate(0, 0, days+1).Add(-time.Microsecond)
+    scheduled := make(map[time.Time]float64)
+    for _, rt := range recurring {
+        for _, date := range rt.Occurrences(now, horizonEnd) {
+            day := dayStart(date.In(today.Location()))
+            if !day.After(today) {
+                day = today.AddDate(0, 0, 1)
+            }
+            scheduled[day] += signedAmount(rt.Type, rt.Amount)
+        }
+    }
+
+    projected := balance
+    for i := 1; i <= days; i++ {
+        date := today.AddDate(0, 0, i)
+
+        var baseline float64
+        for key, amount := range daily {
+            baseline += signedAmount(key.Type, amount) * seasonalFactor(key, date.Month()) / lookbackFactor[key]
+        }
+
+        projected += scheduled[date] + baseline
+        band := forecastZ * deviation * math.Sqrt(float64(i))
+        day := ForecastDay{
+            Date:      date,
+            Scheduled: scheduled[date],
+            Baseline:  baseline,
+            Balance:   projected,
+            Lower:     projected - band,
+            Upper:     projected + band,
+        }
+        forecast.Days = append(forecast.Days, day)
+
+        if forecast.FirstNegativeDate == nil && day.Balance < 0 {
+            forecast.FirstNegativeDate = &day.Date
+        }
+        if forecast.FirstRiskDate == nil && day.Lower < 0 {
+            forecast.FirstRiskDate = &day.Date
+        }
+    }
+
+    return forecast, nil
+}
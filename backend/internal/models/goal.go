@@ -0,0 +1,313 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "finance/internal/db"
+    "github.com/lib/pq"
+    "math"
+    "time"
+)
+
+// goalRateWindow - период, по которому оценивается текущий темп накоплений
+const goalRateWindow = 90 * 24 * time.Hour
+
+// averageMonth - средняя длина месяца
+const averageMonth = time.Duration(30.44 * 24 * float64(time.Hour))
+
+// goalMaxProjectionMonths - горизонт прогноза: при более медленном темпе дата не считается
+const goalMaxProjectionMonths = 100 * 12
+
+// Goal - цель накоплений ("Отпуск: 150 000 к июню").
+// Если указана категория, в цель засчитываются все транзакции этой категории
+// после создания цели, кроме того, взносы можно добавлять вручную
+type Goal struct {
+    ID           uint       `json:"id"`
+    UserID       uint       `json:"user_id"`
+    Name         string     `json:"name"`
+    TargetAmount float64    `json:"target_amount"`
+    Deadline     *time.Time `json:"deadline"`
+    CategoryID   *uint      `json:"category_id"`
+    CreatedAt    time.Time  `json:"created_at"`
+
+    Saved           float64    `json:"saved"`
+    Remaining       float64    `json:"remaining"`
+    Progress        float64    `json:"progress"`
+    MonthlyRate     float64    `json:"monthly_rate"`
+    RequiredMonthly *float64   `json:"required_monthly"`
+    ProjectedDate   *time.Time `json:"projected_date"`
+
+    Contributions []GoalContribution `json:"contributions,omitempty"`
+}
+
+// GoalContribution - взнос в цель: ручной или транзакция связанной категории
+type GoalContribution struct {
+    ID            *uint     `json:"id"`
+    TransactionID *uint     `json:"transaction_id"`
+    Amount        float64   `json:"amount"`
+    Date          time.Time `json:"date"`
+}
+
+func CreateGoal(userID uint, name string, targetAmount float64, deadline *time.Time, categoryID *uint) (*Goal, error) {
+    g := Goal{
+        UserID:       userID,
+        Name:         name,
+        TargetAmount: targetAmount,
+        Deadline:     deadline,
+        CategoryID:   categoryID,
+    }
+    err := db.DB.QueryRow(
+        `INSERT INTO goals (user_id, name, target_amount, deadline, category_id)
+         VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+        userID, name, targetAmount, deadline, categoryID,
+    ).Scan(&g.ID, &g.CreatedAt)
+    if err != nil {
+        return nil, err
+    }
+
+    g.calculateProgress(time.Now())
+    return &g, nil
+}
+
+func queryGoals(query string, args ...interface{}) ([]Goal, error) {
+    rows, err := db.DB.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var goals []Goal
+    for rows.Next() {
+        var g Goal
+        err := rows.Scan(&g.ID, &g.UserID, &g.Name, &g.TargetAmount, &g.Deadline, &g.CategoryID, &g.CreatedAt)
+        if err != nil {
+            return nil, err
+        }
+        goals = append(goals, g)
+    }
+    return goals, rows.Err()
+}
+
+// GetUserGoals возвращает цели пользователя с прогрессом на момент now
+func GetUserGoals(userID uint, now time.Time) ([]Goal, error) {
+    goals, err := queryGoals(
+        `SELECT id, user_id, name, target_amount, deadline, category_id, created_at
+         FROM goals WHERE user_id = $1 ORDER BY deadline NULLS LAST, id`,
+        userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    for i := range goals {
+        if err := goals[i].loadContributions(); err != nil {
+            return nil, err
+        }
+        goals[i].calculateProgress(now)
+        goals[i].Contributions = nil
+    }
+    return goals, nil
+}
+
+// GetGoal возвращает цель вместе со списком взносов
+func GetGoal(id, userID uint, now time.Time) (*Goal, error) {
+    goals, err := queryGoals(
+        `SELECT id, user_id, name, target_amount, deadline, category_id, created_at
+         FROM goals WHERE id = $1 AND user_id = $2`,
+        id, userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    if len(goals) == 0 {
+        return nil, ErrNotFound
+    }
+
+    g := &goals[0]
+    if err := g.loadContributions(); err != nil {
+        return nil, err
+    }
+    g.calculateProgress(now)
+    return g, nil
+}
+
+func DeleteGoal(id, userID uint) error {
+    result, err := db.DB.Exec("DELETE FROM goals WHERE id = $1 AND user_id = $2", id, userID)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
+}
+
+// AddGoalContribution добавляет взнос в цель. Если указана транзакция пользователя,
+// сумма и дата берутся из нее, когда они не заданы явно
+func AddGoalContribution(ctx context.Context, goalID, userID uint, transactionID *uint, amount float64, date time.Time) (*GoalContribution, error) {
+    var contribution GoalContribution
+
+    err := db.WithTx(ctx, func(tx db.Querier) error {
+        var exists bool
+        err := tx.QueryRowContext(ctx,
+            "SELECT EXISTS (SELECT 1 FROM goals WHERE id = $1 AND user_id = $2)",
+            goalID, userID,
+        ).Scan(&exists)
+        if err != nil {
+            return err
+        }
+        if !exists {
+            return ErrNotFound
+        }
+
+        if transactionID != nil {
+            var transactionAmount float64
+            var transactionDate time.Time
+            err := tx.QueryRowContext(ctx,
+                "SELECT amount, date FROM transactions WHERE id = $1 AND user_id = $2",
+                *transactionID, userID,
+            ).Scan(&transactionAmount, &transactionDate)
+            if err == sql.ErrNoRows {
+                return ErrNotFound
+            }
+            if err != nil {
+                return err
+            }
+            if amount == 0 {
+                amount = transactionAmount
+            }
+            if date.IsZero() {
+                date = transactionDate
+            }
+        }
+        if date.IsZero() {
+            date = time.Now()
+        }
+
+        var id uint
+        err = tx.QueryRowContext(ctx,
+            `INSERT INTO goal_contributions (goal_id, transaction_id, amount, date)
+             VALUES ($1, $2, $3, $4) RETURNING id`,
+            goalID, transactionID, amount, date,
+        ).Scan(&id)
+        // Одна транзакция засчитывается в цель только один раз
+        var pqErr *pq.Error
+        if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+            return ErrAlreadyExists
+        }
+        if err != nil {
+            return err
+        }
+
+        contribution = GoalContribution{
+            ID:            &id,
+            TransactionID: transactionID,
+            Amount:        amount,
+            Date:          date,
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &contribution, nil
+}
+
+// loadContributions собирает ручные взносы и транзакции связанной категории.
+// Транзакция, уже добавленная взносом вручную, второй раз не учитывается
+func (g *Goal) loadContributions() error {
+    rows, err := db.DB.Query(
+        `SELECT id, transaction_id, amount, date
+         FROM goal_contributions
+         WHERE goal_id = $1
+         UNION ALL
+         SELECT NULL, t.id, t.amount, t.date
+         FROM transactions t
+         WHERE t.user_id = $2
+         AND t.category_id = $3
+         AND t.date >= $4
+         AND NOT EXISTS (
+             SELECT 1 FROM goal_contributions gc WHERE gc.goal_id = $1 AND gc.transaction_id = t.id
+         )
+         ORDER BY date`,
+        g.ID, g.UserID, g.CategoryID, g.CreatedAt,
+    )
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    g.Contributions = nil
+    for rows.Next() {
+        var c GoalContribution
+        if err := rows.Scan(&c.ID, &c.TransactionID, &c.Amount, &c.Date); err != nil {
+            return err
+        }
+        g.Contributions = append(g.Contributions, c)
+    }
+    return rows.Err()
+}
+
+// calculateProgress считает накопленное, нужный ежемесячный взнос до дедлайна
+// и дату достижения цели при темпе взносов за последние 90 дней
+func (g *Goal) calculateProgress(now time.Time) {
+    g.Saved = 0
+    var recent float64
+    for _, c := range g.Contributions {
+        g.Saved += c.Amount
+        if now.Sub(c.Date) <= goalRateWindow {
+            recent += c.Amount
+        }
+    }
+
+    g.Remaining = g.TargetAmount - g.Saved
+    if g.Remaining < 0 {
+        g.Remaining = 0
+    }
+    if g.TargetAmount > 0 {
+        g.Progress = g.Saved / g.TargetAmount * 100
+    }
+
+    // Для новой цели темп считается с момента создания, а не за полные 90 дней
+    window := goalRateWindow
+    if age := now.Sub(g.CreatedAt); age < window {
+        window = age
+    }
+    if window < averageMonth {
+        window = averageMonth
+    }
+    g.MonthlyRate = recent / (float64(window) / float64(averageMonth))
+
+    g.RequiredMonthly = nil
+    if g.Deadline != nil && g.Remaining > 0 {
+        months := float64(g.Deadline.Sub(now)) / float64(averageMonth)
+        if months < 1 {
+            months = 1
+        }
+        required := g.Remaining / months
+        g.RequiredMonthly = &required
+    }
+
+    g.ProjectedDate = nil
+    if g.Remaining == 0 {
+        projected := now
+        if n := len(g.Contributions); n > 0 {
+            projected = g.Contributions[n-1].Date
+        }
+        g.ProjectedDate = &projected
+    } else if g.MonthlyRate > 0 {
+        // Считаем в месяцах, а не в time.Duration: при малом темпе длительность переполняется
+        if months := math.Ceil(g.Remaining / g.MonthlyRate); months <= goalMaxProjectionMonths {
+            projected := now.AddDate(0, int(months), 0)
+            g.ProjectedDate = &projected
+        }
+    }
+}
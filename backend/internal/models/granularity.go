@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// Гранулярность рядов статистики
+const (
+    GranularityDay     = "day"
+    GranularityWeek    = "week"
+    GranularityMonth   = "month"
+    GranularityQuarter = "quarter"
+    GranularityYear    = "year"
+)
+
+func ValidGranularity(granularity string) bool {
+    switch granularity {
+    case GranularityDay, GranularityWeek, GranularityMonth, GranularityQuarter, GranularityYear:
+        return true
+    }
+    return false
+}
+
+// BucketBounds возвращает начало и конец интервала, в который попадает date.
+// weekStart - первый день недели (1 - понедельник, 7 - воскресенье),
+// monthStart - день начала финансового месяца, от него же отсчитываются кварталы и годы
+func BucketBounds(granularity string, weekStart, monthStart int, date time.Time) (time.Time, time.Time) {
+    switch granularity {
+    case GranularityWeek:
+        return PeriodBounds(PeriodWeekly, weekStart, date)
+    case GranularityMonth:
+        return PeriodBounds(PeriodMonthly, monthStart, date)
+    case GranularityQuarter:
+        return PeriodBounds(PeriodQuarterly, monthStart, date)
+    case GranularityYear:
+        return PeriodBounds(PeriodYearly, monthStart, date)
+    }
+    start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+    return start, start.AddDate(0, 0, 1).Add(-time.Microsecond)
+}
+
+// bucketTotals группирует дневные значения по интервалам с startDate по endDate,
+// пустые интервалы заполняются нулем. Для остатков (cumulative) значением интервала
+// становится последнее значение внутри него, иначе значения суммируются
+func bucketTotals(daily []DailyTotal, granularity string, weekStart, monthStart int, startDate, endDate time.Time, totalType string, cumulative bool) []DailyTotal {
+    loc := startDate.Location()
+    values := make(map[time.Time]float64)
+    seen := make(map[time.Time]bool)
+    for _, dt := range daily {
+        // DATE приходит из базы полуночью UTC, переносим день в зону запроса
+        day := time.Date(dt.Date.Year(), dt.Date.Month(), dt.Date.Day(), 0, 0, 0, 0, loc)
+        bucket, _ := BucketBounds(granularity, weekStart, monthStart, day)
+        if cumulative {
+            values[bucket] = dt.Total
+        } else {
+            values[bucket] += dt.Total
+        }
+        seen[bucket] = true
+    }
+
+    var result []DailyTotal
+    var last float64
+    bucket, bucketEnd := BucketBounds(granularity, weekStart, monthStart, startDate)
+    for !bucket.After(endDate) {
+        value := values[bucket]
+        if cumulative {
+            // Остаток без движений в интервале равен остатку предыдущего
+            if seen[bucket] {
+                last = value
+            }
+            value = last
+        }
+        result = append(result, DailyTotal{Date: bucket, Total: value, Type: totalType})
+        bucket, bucketEnd = BucketBounds(granularity, weekStart, monthStart, bucketEnd.Add(time.Microsecond))
+    }
+    return result
+}
+
+// GetBucketedTotals возвращает доходы или расходы по интервалам гранулярности
+func GetBucketedTotals(userID uint, startDate, endDate time.Time, transactionType, granularity string, weekStart, monthStart int) ([]DailyTotal, error) {
+    daily, err := GetDailyTotals(userID, startDate, endDate, transactionType)
+    if err != nil {
+        return nil, err
+    }
+    return bucketTotals(daily, granularity, weekStart, monthStart, startDate, endDate, transactionType, false), nil
+}
+
+// GetBucketedBalance возвращает остаток на конец каждого интервала
+func GetBucketedBalance(userID uint, startDate, endDate time.Time, granularity string, weekStart, monthStart int) ([]DailyTotal, error) {
+    daily, err := GetBalanceHistory(userID, startDate, endDate)
+    if err != nil {
+        return nil, err
+    }
+    return bucketTotals(daily, granularity, weekStart, monthStart, startDate, endDate, "balance", true), nil
+}
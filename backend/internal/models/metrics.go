@@ -0,0 +1,284 @@
+package models
+
+import (
+    "finance/internal/db"
+    "github.com/lib/pq"
+    "math"
+    "time"
+)
+
+const (
+    // Волатильность дохода в ряду считается по скользящему окну месяцев
+    metricsVolatilityMonths = 6
+    metricsMinVolatility    = 3
+    // Наибольшее окно одного запроса
+    maxMetricsMonths = 120
+)
+
+// FinancialMetrics - показатели финансового здоровья за окно.
+// Показатели, которые нельзя посчитать (нет доходов или расходов), равны null
+type FinancialMetrics struct {
+    StartDate time.Time `json:"start_date"`
+    EndDate   time.Time `json:"end_date"`
+    Income    float64   `json:"income"`
+    Expenses  float64   `json:"expenses"`
+    // Доля сбереженного дохода, %
+    SavingsRate *float64 `json:"savings_rate"`
+    // Расходы на единицу дохода
+    ExpenseRatio *float64 `json:"expense_ratio"`
+    // Остаток на конец окна
+    Balance         float64 `json:"balance"`
+    AverageExpenses float64 `json:"average_expenses"`
+    // На сколько месяцев средних расходов хватит ликвидных средств: оценок счетов
+    // из чистой стоимости, а если счетов нет - остатка
+    RunwayMonths *float64 `json:"runway_months"`
+    // Коэффициент вариации месячного дохода
+    IncomeVolatility *float64 `json:"income_volatility"`
+    FixedExpenses    float64  `json:"fixed_expenses"`
+    VariableExpenses float64  `json:"variable_expenses"`
+    // Доля постоянных расходов, %
+    FixedShare *float64 `json:"fixed_share"`
+
+    Months []MonthlyMetrics `json:"months"`
+}
+
+// MonthlyMetrics - те же показатели за отдельный месяц. Запас считается по средним
+// расходам с начала окна, волатильность - по последним шести месяцам
+type MonthlyMetrics struct {
+    Month            time.Time `json:"month"`
+    Income           float64   `json:"income"`
+    Expenses         float64   `json:"expenses"`
+    SavingsRate      *float64  `json:"savings_rate"`
+    ExpenseRatio     *float64  `json:"expense_ratio"`
+    Balance          float64   `json:"balance"`
+    RunwayMonths     *float64  `json:"runway_months"`
+    IncomeVolatility *float64  `json:"income_volatility"`
+    FixedExpenses    float64   `json:"fixed_expenses"`
+    VariableExpenses float64   `json:"variable_expenses"`
+    FixedShare       *float64  `json:"fixed_share"`
+}
+
+func ratio(value, base float64) *float64 {
+    if base <= 0 {
+        return nil
+    }
+    r := value / base
+    return &r
+}
+
+func percent(value, base float64) *float64 {
+    r := ratio(value, base)
+    if r != nil {
+        *r *= 100
+    }
+    return r
+}
+
+// variation возвращает коэффициент вариации выборки
+func variation(values []float64) *float64 {
+    if len(values) == 0 {
+        return nil
+    }
+    var mean, variance float64
+    for _, v := range values {
+        mean += v
+    }
+    mean /= float64(len(values))
+    for _, v := range values {
+        variance += (v - mean) * (v - mean)
+    }
+    return ratio(math.Sqrt(variance/float64(len(values))), mean)
+}
+
+// runway - на сколько месяцев расходов expenses хватит средств funds.
+// Отрицательный остаток означает, что запаса нет
+func runway(funds, expenses float64) *float64 {
+    return ratio(math.Max(funds, 0), expenses)
+}
+
+// liquidAssetsQuery суммирует последние оценки счетов пользователя $1 на каждую дату из $2
+// и считает, сколько счетов было оценено к этой дате
+const liquidAssetsQuery = `
+    SELECT d.n, COUNT(v.value), COALESCE(SUM(v.value), 0)
+    FROM unnest($2::date[]) WITH ORDINALITY AS d(date, n)
+    LEFT JOIN net_worth_items i ON i.user_id = $1 AND i.type = 'asset' AND i.kind = 'account'
+    LEFT JOIN LATERAL (
+        SELECT value FROM valuations
+        WHERE item_id = i.id AND date <= d.date
+        ORDER BY date DESC LIMIT 1
+    ) v ON true
+    GROUP BY d.n
+    ORDER BY d.n`
+
+// getLiquidAssets возвращает сумму счетов на каждую дату, nil - если счетов еще нет
+func getLiquidAssets(userID uint, dates []time.Time) ([]*float64, error) {
+    days := make([]string, len(dates))
+    for i, date := range dates {
+        days[i] = rollupDay(date)
+    }
+
+    rows, err := db.DB.Query(liquidAssetsQuery, userID, pq.Array(days))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    assets := make([]*float64, len(dates))
+    for rows.Next() {
+        var n, count int
+        var total float64
+        if err := rows.Scan(&n, &count, &total); err != nil {
+            return nil, err
+        }
+        if count > 0 {
+            assets[n-1] = &total
+        }
+    }
+    return assets, rows.Err()
+}
+
+// fixedExpenseMatcher определяет постоянные расходы: транзакции, совпадающие
+// с регулярным расходом по описанию или по категории и сумме
+func fixedExpenseMatcher(userID uint) (func(Transaction) bool, error) {
+    recurring, err := GetUserRecurringTransactions(userID)
+    if err != nil {
+        return nil, err
+    }
+
+    keys := make(map[string]bool)
+    var templates []RecurringTransaction
+    for _, rt := range recurring {
+        if rt.Type != TransactionTypeExpense {
+            continue
+        }
+        if key := subscriptionKey(rt.Description); key != "" {
+            keys[key] = true
+        }
+        templates = append(templates, rt)
+    }
+
+    return func(t Transaction) bool {
+        if keys[subscriptionKey(t.Description)] {
+            return true
+        }
+        for _, rt := range templates {
+            if rt.CategoryID != nil && t.CategoryID != nil && *rt.CategoryID == *t.CategoryID &&
+                withinTolerance(t.Amount, rt.Amount) {
+                return true
+            }
+        }
+        return false
+    }, nil
+}
+
+// GetFinancialMetrics считает показатели за окно с startDate по endDate
+// и их ряд по финансовым месяцам, начинающимся в день monthStart
+func GetFinancialMetrics(userID uint, startDate, endDate time.Time, monthStart int) (*FinancialMetrics, error) {
+    if endDate.After(startDate.AddDate(0, maxMetricsMonths, 0)) {
+        return nil, ErrRangeTooLarge
+    }
+
+    balance, err := GetCurrentBalance(userID, startDate.Add(-time.Microsecond))
+    if err != nil {
+        return nil, err
+    }
+
+    transactions, err := GetUserTransactionsInRange(userID, startDate, endDate)
+    if err != nil {
+        return nil, err
+    }
+
+    isFixed, err := fixedExpenseMatcher(userID)
+    if err != nil {
+        return nil, err
+    }
+
+    loc := startDate.Location()
+    byMonth := make(map[time.Time]*MonthlyMetrics)
+    for _, t := range transactions {
+        month, _ := PeriodBounds(PeriodMonthly, monthStart, t.Date.In(loc))
+        m := byMonth[month]
+        if m == nil {
+            m = &MonthlyMetrics{Month: month}
+            byMonth[month] = m
+        }
+
+        if t.Type == TransactionTypeIncome {
+            m.Income += t.Amount
+        } else if isFixed(t) {
+            m.FixedExpenses += t.Amount
+        } else {
+            m.VariableExpenses += t.Amount
+        }
+    }
+
+    metrics := &FinancialMetrics{StartDate: startDate, EndDate: endDate}
+    var incomes, averages []float64
+    var monthEnds []time.Time
+    month, monthEnd := PeriodBounds(PeriodMonthly, monthStart, startDate)
+    for !month.After(endDate) {
+        m := MonthlyMetrics{Month: month}
+        if found := byMonth[month]; found != nil {
+            m = *found
+        }
+        m.Expenses = m.FixedExpenses + m.VariableExpenses
+        balance += m.Income - m.Expenses
+        m.Balance = balance
+
+        metrics.Income += m.Income
+        metrics.FixedExpenses += m.FixedExpenses
+        metrics.VariableExpenses += m.VariableExpenses
+        incomes = append(incomes, m.Income)
+
+        m.SavingsRate = percent(m.Income-m.Expenses, m.Income)
+        m.ExpenseRatio = ratio(m.Expenses, m.Income)
+        m.FixedShare = percent(m.FixedExpenses, m.Expenses)
+        averages = append(averages, (metrics.FixedExpenses+metrics.VariableExpenses)/float64(len(incomes)))
+        if monthEnd.After(endDate) {
+            monthEnds = append(monthEnds, endDate)
+        } else {
+            monthEnds = append(monthEnds, monthEnd)
+        }
+        if len(incomes) >= metricsMinVolatility {
+            recent := incomes
+            if len(recent) > metricsVolatilityMonths {
+                recent = recent[len(recent)-metricsVolatilityMonths:]
+            }
+            m.IncomeVolatility = variation(recent)
+        }
+
+        metrics.Months = append(metrics.Months, m)
+        month, monthEnd = PeriodBounds(PeriodMonthly, monthStart, monthEnd.Add(time.Microsecond))
+    }
+
+    metrics.Expenses = metrics.FixedExpenses + metrics.VariableExpenses
+    metrics.Balance = balance
+    if len(incomes) > 0 {
+        metrics.AverageExpenses = metrics.Expenses / float64(len(incomes))
+    }
+    metrics.SavingsRate = percent(metrics.Income-metrics.Expenses, metrics.Income)
+    metrics.ExpenseRatio = ratio(metrics.Expenses, metrics.Income)
+
+    if len(monthEnds) > 0 {
+        liquid, err := getLiquidAssets(userID, monthEnds)
+        if err != nil {
+            return nil, err
+        }
+        funds := metrics.Balance
+        for i := range metrics.Months {
+            m := &metrics.Months[i]
+            funds = m.Balance
+            if liquid[i] != nil {
+                funds = *liquid[i]
+            }
+            m.RunwayMonths = runway(funds, averages[i])
+        }
+        metrics.RunwayMonths = runway(funds, metrics.AverageExpenses)
+    }
+    if len(incomes) >= metricsMinVolatility {
+        metrics.IncomeVolatility = variation(incomes)
+    }
+    metrics.FixedShare = percent(metrics.FixedExpenses, metrics.Expenses)
+
+    return metrics, nil
+}
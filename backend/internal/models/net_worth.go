@@ -0,0 +1,334 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "finance/internal/db"
+    "github.com/lib/pq"
+    "time"
+)
+
+const (
+    NetWorthAsset     = "asset"
+    NetWorthLiability = "liability"
+)
+
+// Виды активов и обязательств
+const (
+    NetWorthKindAccount    = "account"
+    NetWorthKindProperty   = "property"
+    NetWorthKindInvestment = "investment"
+    NetWorthKindLoan       = "loan"
+    NetWorthKindCreditCard = "credit_card"
+    NetWorthKindOther      = "other"
+)
+
+// NetWorthItem - актив или обязательство пользователя. Стоимость задается
+// датированными оценками, на любую дату действует последняя оценка до нее.
+// Обязательства хранятся положительными суммами
+type NetWorthItem struct {
+    ID        uint       `json:"id"`
+    UserID    uint       `json:"user_id"`
+    Name      string     `json:"name"`
+    Type      string     `json:"type"`
+    Kind      string     `json:"kind"`
+    CreatedAt time.Time  `json:"created_at"`
+    Value     float64    `json:"value"`
+    ValuedAt  *time.Time `json:"valued_at"`
+}
+
+type Valuation struct {
+    ID     uint      `json:"id"`
+    ItemID uint      `json:"item_id"`
+    Value  float64   `json:"value"`
+    Date   time.Time `json:"date"`
+}
+
+type NetWorthPoint struct {
+    Date        time.Time `json:"date"`
+    Assets      float64   `json:"assets"`
+    Liabilities float64   `json:"liabilities"`
+    NetWorth    float64   `json:"net_worth"`
+    // Значение взято из сохраненного снимка, а не пересчитано по оценкам
+    Snapshot bool `json:"snapshot"`
+}
+
+func ValidNetWorthType(itemType string) bool {
+    return itemType == NetWorthAsset || itemType == NetWorthLiability
+}
+
+func ValidNetWorthKind(kind string) bool {
+    switch kind {
+    case NetWorthKindAccount, NetWorthKindProperty, NetWorthKindInvestment,
+        NetWorthKindLoan, NetWorthKindCreditCard, NetWorthKindOther:
+        return true
+    }
+    return false
+}
+
+// CreateNetWorthItem добавляет актив или обязательство с начальной оценкой
+func CreateNetWorthItem(ctx context.Context, userID uint, name, itemType, kind string, value float64, date time.Time) (*NetWorthItem, error) {
+    item := NetWorthItem{
+        UserID: userID,
+        Name:   name,
+        Type:   itemType,
+        Kind:   kind,
+        Value:  value,
+    }
+
+    err := db.WithTx(ctx, func(tx db.Querier) error {
+        err := tx.QueryRowContext(ctx,
+            `INSERT INTO net_worth_items (user_id, name, type, kind)
+             VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+            userID, name, itemType, kind,
+        ).Scan(&item.ID, &item.CreatedAt)
+        if err != nil {
+            return err
+        }
+
+        _, err = upsertValuation(ctx, tx, item.ID, value, date)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    item.ValuedAt = &date
+    return &item, nil
+}
+
+// GetNetWorthItems возвращает активы и обязательства с их оценкой на дату date
+func GetNetWorthItems(userID uint, date time.Time) ([]NetWorthItem, error) {
+    rows, err := db.DB.Query(
+        `SELECT i.id, i.user_id, i.name, i.type, i.kind, i.created_at, v.value, v.date
+         FROM net_worth_items i
+         LEFT JOIN LATERAL (
+             SELECT value, date FROM valuations
+             WHERE item_id = i.id AND date <= $2
+             ORDER BY date DESC LIMIT 1
+         ) v ON true
+         WHERE i.user_id = $1
+         ORDER BY i.type, i.name`,
+        userID, date,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var items []NetWorthItem
+    for rows.Next() {
+        var item NetWorthItem
+        var value sql.NullFloat64
+        err := rows.Scan(&item.ID, &item.UserID, &item.Name, &item.Type, &item.Kind, &item.CreatedAt, &value, &item.ValuedAt)
+        if err != nil {
+            return nil, err
+        }
+        item.Value = value.Float64
+        items = append(items, item)
+    }
+    return items, rows.Err()
+}
+
+func DeleteNetWorthItem(id, userID uint) error {
+    result, err := db.DB.Exec("DELETE FROM net_worth_items WHERE id = $1 AND user_id = $2", id, userID)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
+}
+
+// AddValuation записывает оценку на дату. Повторная оценка за тот же день заменяет предыдущую
+func AddValuation(ctx context.Context, itemID, userID uint, value float64, date time.Time) (*Valuation, error) {
+    var valuation *Valuation
+
+    err := db.WithTx(ctx, func(tx db.Querier) error {
+        var exists bool
+        err := tx.QueryRowContext(ctx,
+            "SELECT EXISTS (SELECT 1 FROM net_worth_items WHERE id = $1 AND user_id = $2)",
+            itemID, userID,
+        ).Scan(&exists)
+        if err != nil {
+            return err
+        }
+        if !exists {
+            return ErrNotFound
+        }
+
+        valuation, err = upsertValuation(ctx, tx, itemID, value, date)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return valuation, nil
+}
+
+func upsertValuation(ctx context.Context, q db.Querier, itemID uint, value float64, date time.Time) (*Valuation, error) {
+    v := Valuation{ItemID: itemID, Value: value}
+    err := q.QueryRowContext(ctx,
+        `INSERT INTO valuations (item_id, value, date) VALUES ($1, $2, $3)
+         ON CONFLICT (item_id, date) DO UPDATE SET value = EXCLUDED.value
+         RETURNING id, date`,
+        itemID, value, date,
+    ).Scan(&v.ID, &v.Date)
+    if err != nil {
+        return nil, err
+    }
+    return &v, nil
+}
+
+// GetValuations возвращает историю оценок актива или обязательства
+func GetValuations(itemID, userID uint) ([]Valuation, error) {
+    rows, err := db.DB.Query(
+        `SELECT v.id, v.item_id, v.value, v.date
+         FROM valuations v
+         JOIN net_worth_items i ON i.id = v.item_id
+         WHERE v.item_id = $1 AND i.user_id = $2
+         ORDER BY v.date`,
+        itemID, userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var valuations []Valuation
+    for rows.Next() {
+        var v Valuation
+        if err := rows.Scan(&v.ID, &v.ItemID, &v.Value, &v.Date); err != nil {
+            return nil, err
+        }
+        valuations = append(valuations, v)
+    }
+    return valuations, rows.Err()
+}
+
+// netWorthQuery считает сумму активов и обязательств пользователя $1 на дату $2
+// по последним оценкам не позже этой даты
+const netWorthQuery = `
+    SELECT
+        COALESCE(SUM(v.value) FILTER (WHERE i.type = 'asset'), 0),
+        COALESCE(SUM(v.value) FILTER (WHERE i.type = 'liability'), 0)
+    FROM net_worth_items i
+    JOIN LATERAL (
+        SELECT value FROM valuations
+        WHERE item_id = i.id AND date <= $2
+        ORDER BY date DESC LIMIT 1
+    ) v ON true
+    WHERE i.user_id = $1`
+
+// GetNetWorth считает чистую стоимость на дату по текущим оценкам
+func GetNetWorth(userID uint, date time.Time) (*NetWorthPoint, error) {
+    p := NetWorthPoint{Date: date}
+    err := db.DB.QueryRow(netWorthQuery, userID, date).Scan(&p.Assets, &p.Liabilities)
+    if err != nil {
+        return nil, err
+    }
+    p.NetWorth = p.Assets - p.Liabilities
+    return &p, nil
+}
+
+// maxNetWorthHistoryPoints ограничивает число интервалов в одном запросе истории
+const maxNetWorthHistoryPoints = 1000
+
+// netWorthHistoryQuery считает чистую стоимость пользователя $1 на каждую дату из $2.
+// Если на дату есть снимок, берется он: он отражает оценки, известные на тот момент,
+// даже если позже оценки задним числом изменили
+const netWorthHistoryQuery = `
+    SELECT d.n, s.date IS NOT NULL,
+        COALESCE(s.assets, n.assets), COALESCE(s.liabilities, n.liabilities)
+    FROM unnest($2::date[]) WITH ORDINALITY AS d(date, n)
+    LEFT JOIN net_worth_snapshots s ON s.user_id = $1 AND s.date = d.date
+    CROSS JOIN LATERAL (
+        SELECT
+            COALESCE(SUM(v.value) FILTER (WHERE i.type = 'asset'), 0) AS assets,
+            COALESCE(SUM(v.value) FILTER (WHERE i.type = 'liability'), 0) AS liabilities
+        FROM net_worth_items i
+        JOIN LATERAL (
+            SELECT value FROM valuations
+            WHERE item_id = i.id AND date <= d.date
+            ORDER BY date DESC LIMIT 1
+        ) v ON true
+        WHERE i.user_id = $1
+    ) n
+    ORDER BY d.n`
+
+// GetNetWorthHistory возвращает чистую стоимость на конец каждого интервала
+// одним запросом
+func GetNetWorthHistory(userID uint, startDate, endDate time.Time, granularity string, monthStart int, now time.Time) ([]NetWorthPoint, error) {
+    if endDate.After(now) {
+        endDate = now
+    }
+
+    var history []NetWorthPoint
+    var days []string
+    bucket, bucketEnd := BucketBounds(granularity, 1, monthStart, startDate)
+    for !bucket.After(endDate) {
+        if len(history) == maxNetWorthHistoryPoints {
+            return nil, ErrRangeTooLarge
+        }
+        date := bucketEnd
+        if date.After(endDate) {
+            date = endDate
+        }
+        history = append(history, NetWorthPoint{Date: date})
+        days = append(days, rollupDay(date))
+
+        bucket, bucketEnd = BucketBounds(granularity, 1, monthStart, bucketEnd.Add(time.Microsecond))
+    }
+    if len(history) == 0 {
+        return history, nil
+    }
+
+    rows, err := db.DB.Query(netWorthHistoryQuery, userID, pq.Array(days))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var n int
+        var p NetWorthPoint
+        if err := rows.Scan(&n, &p.Snapshot, &p.Assets, &p.Liabilities); err != nil {
+            return nil, err
+        }
+        p.Date = history[n-1].Date
+        p.NetWorth = p.Assets - p.Liabilities
+        history[n-1] = p
+    }
+    return history, rows.Err()
+}
+
+// TakeNetWorthSnapshots сохраняет чистую стоимость всех пользователей с активами
+// на текущий день в их часовом поясе. Снимок текущего дня обновляется до его окончания
+func TakeNetWorthSnapshots(ctx context.Context, now time.Time) error {
+    _, err := db.DB.ExecContext(ctx,
+        `INSERT INTO net_worth_snapshots (user_id, date, assets, liabilities)
+         SELECT i.user_id, ($1::timestamptz AT TIME ZONE u.timezone)::date,
+             COALESCE(SUM(v.value) FILTER (WHERE i.type = 'asset'), 0),
+             COALESCE(SUM(v.value) FILTER (WHERE i.type = 'liability'), 0)
+         FROM net_worth_items i
+         JOIN users u ON u.id = i.user_id
+         JOIN LATERAL (
+             SELECT value FROM valuations
+             WHERE item_id = i.id AND date <= ($1::timestamptz AT TIME ZONE u.timezone)::date
+             ORDER BY date DESC LIMIT 1
+         ) v ON true
+         GROUP BY i.user_id, u.timezone
+         ON CONFLICT (user_id, date) DO UPDATE
+         SET assets = EXCLUDED.assets, liabilities = EXCLUDED.liabilities`,
+        now,
+    )
+    return err
+}
@@ -1,14 +1,16 @@
 package models
 
 import (
+    "context"
     "time"
-    "todo-app/internal/db"
+    "finance/internal/db"
 )
 
 type Notification struct {
     ID        uint      `json:"id"`
     UserID    uint      `json:"user_id"`
-    TaskID    uint      `json:"task_id"`
+    TaskID    *uint     `json:"task_id,omitempty"`
+    BudgetID  *uint     `json:"budget_id,omitempty"`
     Message   string    `json:"message"`
     CreatedAt time.Time `json:"created_at"`
     Read      bool      `json:"read"`
@@ -23,9 +25,33 @@ func CreateNotification(userID, taskID uint, message string) error {
     return err
 }
 
+// CreateBudgetNotification создает уведомление по бюджету, если уведомления
+// с таким ключом для этого бюджета еще не было
+func CreateBudgetNotification(ctx context.Context, userID, budgetID uint, alertKey, message string) error {
+    _, err := db.DB.ExecContext(ctx,
+        `INSERT INTO notifications (user_id, budget_id, alert_key, message, created_at, read) 
+         VALUES ($1, $2, $3, $4, NOW(), false)
+         ON CONFLICT (budget_id, alert_key) WHERE budget_id IS NOT NULL DO NOTHING`,
+        userID, budgetID, alertKey, message,
+    )
+    return err
+}
+
+// CreateAlertNotification создает уведомление, не привязанное к бюджету,
+// если уведомления с таким ключом у пользователя еще не было
+func CreateAlertNotification(ctx context.Context, userID uint, alertKey, message string) error {
+    _, err := db.DB.ExecContext(ctx,
+        `INSERT INTO notifications (user_id, alert_key, message, created_at, read)
+         VALUES ($1, $2, $3, NOW(), false)
+         ON CONFLICT (user_id, alert_key) WHERE budget_id IS NULL AND alert_key IS NOT NULL DO NOTHING`,
+        userID, alertKey, message,
+    )
+    return err
+}
+
 func GetUserNotifications(userID uint) ([]Notification, error) {
     rows, err := db.DB.Query(
-        `SELECT id, user_id, task_id, message, created_at, read 
+        `SELECT id, user_id, task_id, budget_id, message, created_at, read 
          FROM notifications 
          WHERE user_id = $1 
          ORDER BY created_at DESC`,
@@ -39,7 +65,7 @@ func GetUserNotifications(userID uint) ([]Notification, error) {
     var notifications []Notification
     for rows.Next() {
         var n Notification
-        err := rows.Scan(&n.ID, &n.UserID, &n.TaskID, &n.Message, &n.CreatedAt, &n.Read)
+        err := rows.Scan(&n.ID, &n.UserID, &n.TaskID, &n.BudgetID, &n.Message, &n.CreatedAt, &n.Read)
         if err != nil {
             return nil, err
         }
@@ -77,7 +103,7 @@ func CheckDueTasks() error {
                 due_date < NOW() 
                 OR due_date < NOW() + INTERVAL '1 day'
                 OR due_date < NOW() + INTERVAL '3 days'
-                OR id NOT IN (SELECT task_id FROM notifications)
+                OR id NOT IN (SELECT task_id FROM notifications WHERE task_id IS NOT NULL)
             )
             AND NOT EXISTS (
                 SELECT 1 FROM notifications n 
@@ -0,0 +1,142 @@
+package models
+
+import (
+    "finance/internal/db"
+    "time"
+)
+
+// RecurringTransaction - регулярный платеж или поступление (аренда, зарплата, подписка).
+// StartDate задает первую дату, следующие получаются шагом Period
+type RecurringTransaction struct {
+    ID          uint      `json:"id"`
+    UserID      uint      `json:"user_id"`
+    CategoryID  *uint     `json:"category_id"`
+    Amount      float64   `json:"amount"`
+    Type        string    `json:"type"`
+    Description string    `json:"description"`
+    Period      string    `json:"period"`
+    StartDate   time.Time `json:"start_date"`
+}
+
+// NextPeriodDate возвращает дату, отстоящую от date на один период
+func NextPeriodDate(period string, date time.Time) time.Time {
+    return PeriodDate(period, date, 1)
+}
+
+// PeriodDate возвращает n-е повторение от start. Месяцы отсчитываются от start,
+// а не от предыдущего повторения, и день обрезается до последнего дня месяца:
+// 31 января -> 29 февраля -> 31 марта -> 30 апреля
+func PeriodDate(period string, start time.Time, n int) time.Time {
+    switch period {
+    case PeriodWeekly:
+        return start.AddDate(0, 0, 7*n)
+    case PeriodQuarterly:
+        return addMonths(start, 3*n)
+    case PeriodYearly:
+        return addMonths(start, 12*n)
+    }
+    return addMonths(start, n)
+}
+
+// addMonths сдвигает date на months месяцев без перехода на следующий месяц
+func addMonths(date time.Time, months int) time.Time {
+    year, month, day := date.Date()
+    first := time.Date(year, month+time.Month(months), 1, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+    if last := first.AddDate(0, 1, -1).Day(); day > last {
+        day = last
+    }
+    return first.AddDate(0, 0, day-1)
+}
+
+// Occurrences возвращает даты повторений в промежутке (from, to]
+func (rt RecurringTransaction) Occurrences(from, to time.Time) []time.Time {
+    var dates []time.Time
+    for n := 0; ; n++ {
+        date := PeriodDate(rt.Period, rt.StartDate, n)
+        if date.After(to) {
+            break
+        }
+        if date.After(from) {
+            dates = append(dates, date)
+        }
+    }
+    return dates
+}
+
+func CreateRecurringTransaction(userID uint, categoryID *uint, amount float64, transactionType, description, period string, startDate time.Time) (*RecurringTransaction, error) {
+    var id uint
+    err := db.DB.QueryRow(
+        `INSERT INTO recurring_transactions (user_id, category_id, amount, type, description, period, start_date)
+         VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+        userID, categoryID, amount, transactionType, description, period, startDate,
+    ).Scan(&id)
+    if err != nil {
+        return nil, err
+    }
+
+    return &RecurringTransaction{
+        ID:          id,
+        UserID:      userID,
+        CategoryID:  categoryID,
+        Amount:      amount,
+        Type:        transactionType,
+        Description: description,
+        Period:      period,
+        StartDate:   startDate,
+    }, nil
+}
+
+func GetUserRecurringTransactions(userID uint) ([]RecurringTransaction, error) {
+    rows, err := db.DB.Query(
+        `SELECT id, user_id, category_id, amount, type, description, period, start_date
+         FROM recurring_transactions
+         WHERE user_id = $1
+         ORDER BY start_date`,
+        userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var recurring []RecurringTransaction
+    for rows.Next() {
+        var rt RecurringTransaction
+        err := rows.Scan(&rt.ID, &rt.UserID, &rt.CategoryID, &rt.Amount, &rt.Type, &rt.Description, &rt.Period, &rt.StartDate)
+        if err != nil {
+            return nil, err
+        }
+        recurring = append(recurring, rt)
+    }
+    return recurring, rows.Err()
+}
+
+func DeleteRecurringTransaction(id, userID uint) error {
+    result, err := db.DB.Exec("DELETE FROM recurring_transactions WHERE id = $1 AND user_id = $2", id, userID)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
+}
+
+// upcomingExpenses суммирует регулярные расходы, ожидаемые в промежутке (from, to]
+func upcomingExpenses(recurring []RecurringTransaction, from, to time.Time) float64 {
+    var total float64
+    for _, rt := range recurring {
+        if rt.Type != "expense" {
+            continue
+        }
+        total += rt.Amount * float64(len(rt.Occurrences(from, to)))
+    }
+    return total
+}
\ No newline at end of file
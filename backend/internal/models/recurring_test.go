@@ -0,0 +1,48 @@
+package models
+
+import (
+    "testing"
+    "time"
+)
+
+func TestOccurrencesMonthEnd(t *testing.T) {
+    rt := RecurringTransaction{
+        Period:    PeriodMonthly,
+        StartDate: time.Date(2024, time.January, 31, 10, 0, 0, 0, time.UTC),
+    }
+
+    got := rt.Occurrences(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.May, 31, 23, 0, 0, 0, time.UTC))
+    want := []time.Time{
+        time.Date(2024, time.January, 31, 10, 0, 0, 0, time.UTC),
+        time.Date(2024, time.February, 29, 10, 0, 0, 0, time.UTC),
+        time.Date(2024, time.March, 31, 10, 0, 0, 0, time.UTC),
+        time.Date(2024, time.April, 30, 10, 0, 0, 0, time.UTC),
+        time.Date(2024, time.May, 31, 10, 0, 0, 0, time.UTC),
+    }
+    if len(got) != len(want) {
+        t.Fatalf("got %d occurrences %v, want %d", len(got), got, len(want))
+    }
+    for i := range want {
+        if !got[i].Equal(want[i]) {
+            t.Errorf("occurrence %d = %v, want %v", i, got[i], want[i])
+        }
+    }
+}
+
+func TestNextPeriodDateMonthEnd(t *testing.T) {
+    tests := []struct {
+        period string
+        date   time.Time
+        want   time.Time
+    }{
+        {PeriodMonthly, time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)},
+        {PeriodQuarterly, time.Date(2025, time.November, 30, 0, 0, 0, 0, time.UTC), time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC)},
+        {PeriodYearly, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)},
+        {PeriodWeekly, time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, time.February, 7, 0, 0, 0, 0, time.UTC)},
+    }
+    for _, tt := range tests {
+        if got := NextPeriodDate(tt.period, tt.date); !got.Equal(tt.want) {
+            t.Errorf("NextPeriodDate(%s, %v) = %v, want %v", tt.period, tt.date, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+    "context"
+    "finance/internal/db"
+    "time"
+)
+
+// Агрегаты транзакций по дням и месяцам (daily_rollups, monthly_rollups) в разрезе
+// категории и типа. Обновляются в той же транзакции, что и сами транзакции,
+// и позволяют статистике не сканировать всю историю на каждом запросе
+
+const (
+    RollupsDaily   = "daily_rollups"
+    RollupsMonthly = "monthly_rollups"
+)
+
+// RollupMismatch - расхождение агрегата с пересчитанным значением
+type RollupMismatch struct {
+    Table         string    `json:"table"`
+    UserID        uint      `json:"user_id"`
+    Date          time.Time `json:"date"`
+    CategoryID    *uint     `json:"category_id"`
+    Type          string    `json:"type"`
+    ExpectedTotal float64   `json:"expected_total"`
+    ActualTotal   float64   `json:"actual_total"`
+    ExpectedCount int       `json:"expected_count"`
+    ActualCount   int       `json:"actual_count"`
+}
+
+// rollupDay - календарный день date в ее зоне в виде DATE. Границы запросов к агрегатам
+// передаются в зоне пользователя, в которой агрегаты и собраны
+func rollupDay(date time.Time) string {
+    return date.Format("2006-01-02")
+}
+
+// addRollup учитывает транзакцию в дневном и месячном агрегатах.
+// День и месяц транзакции берутся в часовом поясе пользователя
+func addRollup(ctx context.Context, q db.Querier, userID uint, categoryID *uint, transactionType string, amount float64, date time.Time) error {
+    _, err := q.ExecContext(ctx,
+        `INSERT INTO daily_rollups (user_id, date, category_id, type, total, count)
+         SELECT id, ($2::timestamptz AT TIME ZONE timezone)::date, $3, $4, $5, 1
+         FROM users WHERE id = $1
+         ON CONFLICT (user_id, date, (COALESCE(category_id, 0)), type)
+         DO UPDATE SET total = daily_rollups.total + EXCLUDED.total, count = daily_rollups.count + 1`,
+        userID, date, categoryID, transactionType, amount,
+    )
+    if err != nil {
+        return err
+    }
+
+    _, err = q.ExecContext(ctx,
+        `INSERT INTO monthly_rollups (user_id, month, category_id, type, total, count)
+         SELECT id, date_trunc('month', $2::timestamptz AT TIME ZONE timezone)::date, $3, $4, $5, 1
+         FROM users WHERE id = $1
+         ON CONFLICT (user_id, month, (COALESCE(category_id, 0)), type)
+         DO UPDATE SET total = monthly_rollups.total + EXCLUDED.total, count = monthly_rollups.count + 1`,
+        userID, date, categoryID, transactionType, amount,
+    )
+    return err
+}
+
+// moveRollups переносит агрегаты категории fromID в toID (nil - без категории)
+// при слиянии или удалении категории
+func moveRollups(ctx context.Context, q db.Querier, userID, fromID uint, toID *uint) error {
+    queries := []string{
+        `INSERT INTO daily_rollups (user_id, date, category_id, type, total, count)
+         SELECT user_id, date, $3::integer, type, total, count
+         FROM daily_rollups WHERE user_id = $1 AND category_id = $2
+         ON CONFLICT (user_id, date, (COALESCE(category_id, 0)), type)
+         DO UPDATE SET total = daily_rollups.total + EXCLUDED.total, count = daily_rollups.count + EXCLUDED.count`,
+        `DELETE FROM daily_rollups WHERE user_id = $1 AND category_id = $2`,
+        `INSERT INTO monthly_rollups (user_id, month, category_id, type, total, count)
+         SELECT user_id, month, $3::integer, type, total, count
+         FROM monthly_rollups WHERE user_id = $1 AND category_id = $2
+         ON CONFLICT (user_id, month, (COALESCE(category_id, 0)), type)
+         DO UPDATE SET total = monthly_rollups.total + EXCLUDED.total, count = monthly_rollups.count + EXCLUDED.count`,
+        `DELETE FROM monthly_rollups WHERE user_id = $1 AND category_id = $2`,
+    }
+    for _, query := range queries {
+        if _, err := q.ExecContext(ctx, query, userID, fromID, toID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// rollupRange переводит промежуток в даты для rollupTotalsQuery: первый и последний день
+// и полные месяцы внутри него [monthFrom, monthTo). Полные месяцы читаются из месячных
+// агрегатов, оставшиеся дни по краям - из дневных
+func rollupRange(startDate, endDate time.Time) (string, string, string, string) {
+    monthFrom := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, startDate.Location())
+    if monthFrom.Before(dayStart(startDate)) {
+        monthFrom = monthFrom.AddDate(0, 1, 0)
+    }
+    monthTo := time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, endDate.Location())
+    // Последний месяц полный, только если промежуток доходит до его последнего дня
+    if dayStart(endDate).Equal(monthTo.AddDate(0, 1, -1)) {
+        monthTo = monthTo.AddDate(0, 1, 0)
+    }
+    if monthTo.Before(monthFrom) {
+        monthTo = monthFrom
+    }
+    return rollupDay(startDate), rollupDay(endDate), rollupDay(monthFrom), rollupDay(monthTo)
+}
+
+// rollupTotalsQuery - суммы агрегатов пользователя $1 за дни с $2 по $3,
+// где $4 и $5 - границы полных месяцев из rollupRange
+const rollupTotalsQuery = `
+    SELECT category_id, type, total
+    FROM monthly_rollups
+    WHERE user_id = $1 AND month >= $4::date AND month < $5::date
+    UNION ALL
+    SELECT category_id, type, total
+    FROM daily_rollups
+    WHERE user_id = $1 AND date BETWEEN $2::date AND $3::date
+        AND NOT (date >= $4::date AND date < $5::date)`
+
+// RebuildRollups пересчитывает агрегаты пользователя (или всех пользователей, если userID = nil)
+// из таблицы транзакций
+func RebuildRollups(ctx context.Context, userID *uint) error {
+    return db.WithTx(ctx, func(tx db.Querier) error {
+        return rebuildRollups(ctx, tx, userID)
+    })
+}
+
+// MigrateRollups собирает агрегаты всех пользователей, если этого еще не делали
+// (или они были очищены миграцией схемы)
+func MigrateRollups(ctx context.Context) error {
+    return db.RunMigration(ctx, "rollups_v1", func(tx db.Querier) error {
+        return rebuildRollups(ctx, tx, nil)
+    })
+}
+
+func rebuildRollups(ctx context.Context, q db.Querier, userID *uint) error {
+    queries := []string{
+        `DELETE FROM daily_rollups WHERE $1::integer IS NULL OR user_id = $1`,
+        `DELETE FROM monthly_rollups WHERE $1::integer IS NULL OR user_id = $1`,
+        `INSERT INTO daily_rollups (user_id, date, category_id, type, total, count)
+         SELECT t.user_id, (t.date AT TIME ZONE u.timezone)::date, t.category_id, t.type, SUM(t.amount), COUNT(*)
+         FROM transactions t
+         JOIN users u ON u.id = t.user_id
+         WHERE $1::integer IS NULL OR t.user_id = $1
+         GROUP BY t.user_id, (t.date AT TIME ZONE u.timezone)::date, t.category_id, t.type`,
+        `INSERT INTO monthly_rollups (user_id, month, category_id, type, total, count)
+         SELECT user_id, date_trunc('month', date)::date, category_id, type, SUM(total), SUM(count)
+         FROM daily_rollups
+         WHERE $1::integer IS NULL OR user_id = $1
+         GROUP BY user_id, date_trunc('month', date)::date, category_id, type`,
+    }
+    for _, query := range queries {
+        if _, err := q.ExecContext(ctx, query, userID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// CheckRollups сверяет дневные агрегаты с транзакциями, а месячные - с дневными
+func CheckRollups(ctx context.Context) ([]RollupMismatch, error) {
+    checks := []struct {
+        table string
+        query string
+    }{
+        {RollupsDaily, `
+            WITH expected AS (
+                SELECT t.user_id, (t.date AT TIME ZONE u.timezone)::date AS date, t.category_id, t.type,
+                    SUM(t.amount) AS total, COUNT(*) AS count
+                FROM transactions t
+                JOIN users u ON u.id = t.user_id
+                GROUP BY t.user_id, (t.date AT TIME ZONE u.timezone)::date, t.category_id, t.type
+            )
+            SELECT COALESCE(e.user_id, r.user_id), COALESCE(e.date, r.date), COALESCE(e.category_id, r.category_id),
+                COALESCE(e.type, r.type), COALESCE(e.total, 0), COALESCE(r.total, 0), COALESCE(e.count, 0), COALESCE(r.count, 0)
+            FROM expected e
+            FULL JOIN daily_rollups r ON r.user_id = e.user_id AND r.date = e.date
+                AND COALESCE(r.category_id, 0) = COALESCE(e.category_id, 0) AND r.type = e.type
+            WHERE e.total IS DISTINCT FROM r.total OR e.count IS DISTINCT FROM r.count`},
+        {RollupsMonthly, `
+            WITH expected AS (
+                SELECT user_id, date_trunc('month', date)::date AS month, category_id, type, SUM(total) AS total, SUM(count) AS count
+                FROM daily_rollups
+                GROUP BY user_id, date_trunc('month', date)::date, category_id, type
+            )
+            SELECT COALESCE(e.user_id, r.user_id), COALESCE(e.month, r.month), COALESCE(e.category_id, r.category_id),
+                COALESCE(e.type, r.type), COALESCE(e.total, 0), COALESCE(r.total, 0), COALESCE(e.count, 0), COALESCE(r.count, 0)
+            FROM expected e
+            FULL JOIN monthly_rollups r ON r.user_id = e.user_id AND r.month = e.month
+                AND COALESCE(r.category_id, 0) = COALESCE(e.category_id, 0) AND r.type = e.type
+            WHERE e.total IS DISTINCT FROM r.total OR e.count IS DISTINCT FROM r.count`},
+    }
+
+    var mismatches []RollupMismatch
+    for _, check := range checks {
+        rows, err := db.DB.QueryContext(ctx, check.query)
+        if err != nil {
+            return nil, err
+        }
+        for rows.Next() {
+            m := RollupMismatch{Table: check.table}
+            err := rows.Scan(&m.UserID, &m.Date, &m.CategoryID, &m.Type, &m.ExpectedTotal, &m.ActualTotal, &m.ExpectedCount, &m.ActualCount)
+            if err != nil {
+                rows.Close()
+                return nil, err
+            }
+            mismatches = append(mismatches, m)
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return nil, err
+        }
+    }
+    return mismatches, nil
+}
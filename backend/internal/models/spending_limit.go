@@ -0,0 +1,131 @@
+package models
+
+import (
+    "database/sql"
+    "finance/internal/db"
+    "time"
+)
+
+// SpendingLimit - общий лимит расходов пользователя на период, независимо от категорий
+type SpendingLimit struct {
+    UserID   uint    `json:"user_id"`
+    Amount   float64 `json:"amount"`
+    Period   string  `json:"period"`
+    StartDay int     `json:"start_day"`
+}
+
+type SafeToSpendDay struct {
+    Date        time.Time `json:"date"`
+    Spent       float64   `json:"spent"`
+    SafeToSpend float64   `json:"safe_to_spend"`
+}
+
+type SpendingSummary struct {
+    Limit             float64          `json:"limit"`
+    StartDate         time.Time        `json:"start_date"`
+    EndDate           time.Time        `json:"end_date"`
+    Spent             float64          `json:"spent"`
+    UpcomingRecurring float64          `json:"upcoming_recurring"`
+    Remaining         float64          `json:"remaining"`
+    DaysRemaining     int              `json:"days_remaining"`
+    SafeToSpend       float64          `json:"safe_to_spend"`
+    History           []SafeToSpendDay `json:"history"`
+}
+
+func SetSpendingLimit(userID uint, amount float64, period string, startDay int) (*SpendingLimit, error) {
+    _, err := db.DB.Exec(
+        `INSERT INTO spending_limits (user_id, amount, period, start_day)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (user_id) DO UPDATE SET amount = EXCLUDED.amount, period = EXCLUDED.period, start_day = EXCLUDED.start_day`,
+        userID, amount, period, startDay,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    return &SpendingLimit{
+        UserID:   userID,
+        Amount:   amount,
+        Period:   period,
+        StartDay: startDay,
+    }, nil
+}
+
+func GetSpendingLimit(userID uint) (*SpendingLimit, error) {
+    var l SpendingLimit
+    err := db.DB.QueryRow(
+        "SELECT user_id, amount, period, start_day FROM spending_limits WHERE user_id = $1",
+        userID,
+    ).Scan(&l.UserID, &l.Amount, &l.Period, &l.StartDay)
+    if err == sql.ErrNoRows {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &l, nil
+}
+
+// calendarDays - число календарных дней от from до to по датам в их зоне.
+// Деление прошедших часов ошибается на день, если между датами переводятся часы
+func calendarDays(from, to time.Time) int {
+    fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+    toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+    return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+// GetSpendingSummary считает, сколько можно тратить в день до конца периода:
+// (лимит - потрачено до начала дня - ожидаемые регулярные расходы) / оставшиеся дни.
+// History содержит это значение на утро каждого прошедшего дня периода
+func GetSpendingSummary(userID uint, now time.Time) (*SpendingSummary, error) {
+    limit, err := GetSpendingLimit(userID)
+    if err != nil {
+        return nil, err
+    }
+
+    startDate, endDate := PeriodBounds(limit.Period, limit.StartDay, now)
+
+    dailyTotals, err := GetDailyTotals(userID, startDate, endDate, "expense")
+    if err != nil {
+        return nil, err
+    }
+    spentByDay := make(map[string]float64)
+    for _, dt := range dailyTotals {
+        spentByDay[dt.Date.Format("2006-01-02")] += dt.Total
+    }
+
+    recurring, err := GetUserRecurringTransactions(userID)
+    if err != nil {
+        return nil, err
+    }
+
+    summary := &SpendingSummary{
+        Limit:     limit.Amount,
+        StartDate: startDate,
+        EndDate:   endDate,
+    }
+
+    today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+    for day := startDate; !day.After(today); day = day.AddDate(0, 0, 1) {
+        dayEnd := day.AddDate(0, 0, 1).Add(-time.Microsecond)
+        upcoming := upcomingExpenses(recurring, dayEnd, endDate)
+        daysLeft := calendarDays(day, endDate) + 1
+
+        entry := SafeToSpendDay{
+            Date:        day,
+            Spent:       spentByDay[day.Format("2006-01-02")],
+            SafeToSpend: (limit.Amount - summary.Spent - upcoming) / float64(daysLeft),
+        }
+        summary.History = append(summary.History, entry)
+
+        if day.Equal(today) {
+            summary.UpcomingRecurring = upcoming
+            summary.DaysRemaining = daysLeft
+            summary.SafeToSpend = entry.SafeToSpend
+        }
+        summary.Spent += entry.Spent
+    }
+
+    summary.Remaining = limit.Amount - summary.Spent - summary.UpcomingRecurring
+    return summary, nil
+}
\ No newline at end of file
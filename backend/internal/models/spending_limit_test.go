@@ -0,0 +1,24 @@
+package models
+
+import (
+    "testing"
+    "time"
+    _ "time/tzdata"
+)
+
+func TestCalendarDaysAcrossDST(t *testing.T) {
+    loc, err := time.LoadLocation("America/New_York")
+    if err != nil {
+        t.Fatalf("LoadLocation: %v", err)
+    }
+
+    // 2 ноября 2025 часы переводятся назад, и в этих сутках 25 часов
+    from := time.Date(2025, time.November, 1, 0, 0, 0, 0, loc)
+    to := time.Date(2025, time.November, 30, 23, 59, 59, 0, loc)
+    if got := calendarDays(from, to); got != 29 {
+        t.Errorf("calendarDays = %d, want 29", got)
+    }
+    if got := calendarDays(time.Date(2025, time.November, 2, 0, 0, 0, 0, loc), time.Date(2025, time.November, 2, 23, 0, 0, 0, loc)); got != 0 {
+        t.Errorf("calendarDays within one day = %d, want 0", got)
+    }
+}
@@ -1,15 +1,23 @@
 package models
 
 import (
+    "database/sql"
     "finance/internal/db"
     "time"
 )
 
+// Total категории включает суммы всех ее подкатегорий, OwnTotal - только ее собственные
 type CategoryTotal struct {
     CategoryID   uint    `json:"category_id"`
     CategoryName string  `json:"category_name"`
-    Total       float64 `json:"total"`
-    Type        string  `json:"type"`
+    ParentID     *uint   `json:"parent_id"`
+    Icon         string  `json:"icon"`
+    Color        string  `json:"color"`
+    SortOrder    int     `json:"sort_order"`
+    Total        float64 `json:"total"`
+    OwnTotal     float64 `json:"own_total"`
+    HasChildren  bool    `json:"has_children"`
+    Type         string  `json:"type"`
 }
 
 type DailyTotal struct {
@@ -18,19 +26,38 @@ type DailyTotal struct {
     Type   string    `json:"type"`
 }
 
-func GetCategoryTotals(userID uint, startDate, endDate time.Time) ([]CategoryTotal, error) {
+// GetCategoryTotals возвращает суммы категорий одного уровня дерева: корневых, если
+// parentID не задан, иначе - дочерних категорий parentID. При переходе внутрь категории
+// ее собственные транзакции добавляются отдельной строкой, чтобы сумма уровня сходилась
+func GetCategoryTotals(userID uint, startDate, endDate time.Time, parentID *uint) ([]CategoryTotal, error) {
     query := `
-        SELECT c.id, c.name, c.type, COALESCE(SUM(t.amount), 0) as total
+        WITH RECURSIVE tree AS (
+            SELECT id AS root_id, id
+            FROM categories
+            WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $6::integer
+            UNION
+            SELECT tree.root_id, c.id
+            FROM categories c
+            JOIN tree ON c.parent_id = tree.id
+        ),
+        own AS (
+            SELECT category_id, SUM(total) as total
+            FROM (` + rollupTotalsQuery + `) rollups
+            GROUP BY category_id
+        )
+        SELECT c.id, c.name, c.type, c.parent_id, c.icon, c.color, c.sort_order,
+            COALESCE(SUM(own.total), 0) as total,
+            COALESCE(SUM(own.total) FILTER (WHERE tree.id = c.id), 0) as own_total,
+            EXISTS (SELECT 1 FROM categories ch WHERE ch.parent_id = c.id) as has_children
         FROM categories c
-        LEFT JOIN transactions t ON c.id = t.category_id 
-            AND t.user_id = $1 
-            AND t.date BETWEEN $2 AND $3
-        WHERE c.user_id = $1
-        GROUP BY c.id, c.name, c.type
+        JOIN tree ON tree.root_id = c.id
+        LEFT JOIN own ON own.category_id = tree.id
+        GROUP BY c.id, c.name, c.type, c.parent_id, c.icon, c.color, c.sort_order
         ORDER BY total DESC
     `
 
-    rows, err := db.DB.Query(query, userID, startDate, endDate)
+    startDay, endDay, monthFrom, monthTo := rollupRange(startDate, endDate)
+    rows, err := db.DB.Query(query, userID, startDay, endDay, monthFrom, monthTo, parentID)
     if err != nil {
         return nil, err
     }
@@ -39,28 +66,53 @@ func GetCategoryTotals(userID uint, startDate, endDate time.Time) ([]CategoryTot
     var totals []CategoryTotal
     for rows.Next() {
         var ct CategoryTotal
-        err := rows.Scan(&ct.CategoryID, &ct.CategoryName, &ct.Type, &ct.Total)
+        err := rows.Scan(&ct.CategoryID, &ct.CategoryName, &ct.Type, &ct.ParentID, &ct.Icon, &ct.Color, &ct.SortOrder, &ct.Total, &ct.OwnTotal, &ct.HasChildren)
         if err != nil {
             return nil, err
         }
         totals = append(totals, ct)
     }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    if parentID != nil {
+        var parent CategoryTotal
+        err := db.DB.QueryRow(`
+            SELECT c.id, c.name, c.type, c.parent_id, c.icon, c.color, c.sort_order, COALESCE(SUM(r.total), 0)
+            FROM categories c
+            LEFT JOIN (` + rollupTotalsQuery + `) r ON c.id = r.category_id
+            WHERE c.id = $6 AND c.user_id = $1
+            GROUP BY c.id, c.name, c.type, c.parent_id, c.icon, c.color, c.sort_order`,
+            userID, startDay, endDay, monthFrom, monthTo, *parentID,
+        ).Scan(&parent.CategoryID, &parent.CategoryName, &parent.Type, &parent.ParentID, &parent.Icon, &parent.Color, &parent.SortOrder, &parent.Total)
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        if err != nil {
+            return nil, err
+        }
+        if parent.Total != 0 {
+            parent.OwnTotal = parent.Total
+            totals = append(totals, parent)
+        }
+    }
 
     return totals, nil
 }
 
 func GetDailyTotals(userID uint, startDate, endDate time.Time, transactionType string) ([]DailyTotal, error) {
     query := `
-        SELECT DATE(date) as date, COALESCE(SUM(amount), 0) as total, type
-        FROM transactions
-        WHERE user_id = $1 
-            AND date BETWEEN $2 AND $3
+        SELECT date, COALESCE(SUM(total), 0) as total, type
+        FROM daily_rollups
+        WHERE user_id = $1
+            AND date BETWEEN $2::date AND $3::date
             AND type = $4
-        GROUP BY DATE(date), type
+        GROUP BY date, type
         ORDER BY date
     `
 
-    rows, err := db.DB.Query(query, userID, startDate, endDate, transactionType)
+    rows, err := db.DB.Query(query, userID, rollupDay(startDate), rollupDay(endDate), transactionType)
     if err != nil {
         return nil, err
     }
@@ -79,39 +131,29 @@ func GetDailyTotals(userID uint, startDate, endDate time.Time, transactionType s
     return totals, nil
 }
 
+// GetBalanceHistory возвращает накопленную с startDate разницу доходов и расходов
+// на каждый день промежутка
 func GetBalanceHistory(userID uint, startDate, endDate time.Time) ([]DailyTotal, error) {
     query := `
-        WITH RECURSIVE dates AS (
-            SELECT date_trunc('day', $2::timestamp) as date
-            UNION ALL
-            SELECT date + interval '1 day'
-            FROM dates
-            WHERE date < date_trunc('day', $3::timestamp)
-        ),
-        daily_balance AS (
-            SELECT 
-                d.date,
-                COALESCE(SUM(
-                    CASE 
-                        WHEN t.type = 'income' THEN COALESCE(t.amount, 0)
-                        ELSE -COALESCE(t.amount, 0)
-                    END
-                ), 0) as daily_change
-            FROM dates d
-            LEFT JOIN transactions t 
-                ON date_trunc('day', t.date) = d.date 
-                AND t.user_id = $1
-            GROUP BY d.date
+        WITH daily_balance AS (
+            SELECT
+                date,
+                SUM(CASE WHEN type = 'income' THEN total ELSE -total END) as daily_change
+            FROM daily_rollups
+            WHERE user_id = $1
+                AND date BETWEEN $2::date AND $3::date
+            GROUP BY date
         )
-        SELECT 
-            date,
-            SUM(daily_change) OVER (ORDER BY date) as balance,
+        SELECT
+            d::date as date,
+            SUM(COALESCE(b.daily_change, 0)) OVER (ORDER BY d) as balance,
             'balance' as type
-        FROM daily_balance
-        ORDER BY date
+        FROM generate_series($2::date, $3::date, interval '1 day') d
+        LEFT JOIN daily_balance b ON b.date = d::date
+        ORDER BY d
     `
 
-    rows, err := db.DB.Query(query, userID, startDate, endDate)
+    rows, err := db.DB.Query(query, userID, rollupDay(startDate), rollupDay(endDate))
     if err != nil {
         return nil, err
     }
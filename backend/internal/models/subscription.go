@@ -0,0 +1,244 @@
+package models
+
+import (
+    "finance/internal/db"
+    "math"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+)
+
+const (
+    // История, по которой ищутся регулярные списания
+    subscriptionHistoryYears = 2
+    subscriptionMinCharges   = 3
+    // Допустимое отклонение суммы и интервала от медианы
+    subscriptionTolerance = 0.2
+    // Доля списаний, которые должны укладываться в допуск
+    subscriptionMinRegular = 0.75
+)
+
+// DetectedSubscription - регулярное списание, найденное в истории транзакций.
+// Key - нормализованное описание, по нему подписку подтверждают или скрывают
+type DetectedSubscription struct {
+    Key         string    `json:"key"`
+    Description string    `json:"description"`
+    CategoryID  *uint     `json:"category_id"`
+    Amount      float64   `json:"amount"`
+    Period      string    `json:"period"`
+    Charges     int       `json:"charges"`
+    LastDate    time.Time `json:"last_date"`
+    NextDate    time.Time `json:"next_date"`
+    AnnualCost  float64   `json:"annual_cost"`
+}
+
+// subscriptionDate - дата в описании: 12.03, 2024-03-12, 12/03/24
+var subscriptionDate = regexp.MustCompile(`^[0-9]{1,4}([./-][0-9]{1,2}){1,2}$`)
+
+// subscriptionKey нормализует описание: номера заказов и даты в описании
+// одного и того же платежа обычно меняются от списания к списанию.
+// Удаляются только такие слова целиком, поэтому "Netflix 4K" и "Netflix" различаются
+func subscriptionKey(description string) string {
+    var words []string
+    for _, word := range strings.Fields(strings.ToLower(description)) {
+        if subscriptionNoise(word) {
+            continue
+        }
+        words = append(words, word)
+    }
+    return strings.Join(words, " ")
+}
+
+// subscriptionNoise - слово похоже на дату или идентификатор: число, номер с # или *,
+// код с длинной последовательностью цифр
+func subscriptionNoise(word string) bool {
+    if strings.ContainsAny(word, "#*") || subscriptionDate.MatchString(word) {
+        return true
+    }
+    digits := 0
+    for _, r := range word {
+        if r >= '0' && r <= '9' {
+            digits++
+        }
+    }
+    return digits == len(word) || digits >= 4
+}
+
+// subscriptionPeriod подбирает период по медианному интервалу в днях
+func subscriptionPeriod(days float64) (string, float64, bool) {
+    periods := []struct {
+        period string
+        days   float64
+    }{
+        {PeriodWeekly, 7},
+        {PeriodMonthly, 30.44},
+        {PeriodQuarterly, 91.31},
+        {PeriodYearly, 365.25},
+    }
+    for _, p := range periods {
+        if math.Abs(days-p.days) <= p.days*subscriptionTolerance {
+            return p.period, p.days, true
+        }
+    }
+    return "", 0, false
+}
+
+func withinTolerance(value, expected float64) bool {
+    return math.Abs(value-expected) <= expected*subscriptionTolerance
+}
+
+// DetectSubscriptions ищет расходы с одинаковым описанием, близкой суммой
+// и регулярным интервалом. Уже заведенные регулярные платежи, скрытые пользователем
+// и прекратившиеся списания (пропущено больше половины периода) не возвращаются
+func DetectSubscriptions(userID uint, now time.Time) ([]DetectedSubscription, error) {
+    transactions, err := GetUserTransactionsInRange(userID, now.AddDate(-subscriptionHistoryYears, 0, 0), now)
+    if err != nil {
+        return nil, err
+    }
+
+    known, err := knownSubscriptionKeys(userID)
+    if err != nil {
+        return nil, err
+    }
+
+    groups := make(map[string][]Transaction)
+    for _, t := range transactions {
+        if t.Type != TransactionTypeExpense {
+            continue
+        }
+        key := subscriptionKey(t.Description)
+        if key == "" || known[key] {
+            continue
+        }
+        groups[key] = append(groups[key], t)
+    }
+
+    var detected []DetectedSubscription
+    for key, list := range groups {
+        if len(list) < subscriptionMinCharges {
+            continue
+        }
+        sort.Slice(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+
+        amounts := make([]float64, len(list))
+        intervals := make([]float64, 0, len(list)-1)
+        for i, t := range list {
+            amounts[i] = t.Amount
+            if i > 0 {
+                intervals = append(intervals, t.Date.Sub(list[i-1].Date).Hours()/24)
+            }
+        }
+
+        amount := median(amounts)
+        interval := median(intervals)
+        period, periodDays, ok := subscriptionPeriod(interval)
+        if !ok {
+            continue
+        }
+
+        var regularAmounts, regularIntervals int
+        for _, a := range amounts {
+            if withinTolerance(a, amount) {
+                regularAmounts++
+            }
+        }
+        for _, d := range intervals {
+            if withinTolerance(d, periodDays) {
+                regularIntervals++
+            }
+        }
+        if float64(regularAmounts) < float64(len(amounts))*subscriptionMinRegular ||
+            float64(regularIntervals) < float64(len(intervals))*subscriptionMinRegular {
+            continue
+        }
+
+        last := list[len(list)-1]
+        next := NextPeriodDate(period, last.Date)
+        if now.Sub(next).Hours()/24 > periodDays/2 {
+            continue
+        }
+
+        detected = append(detected, DetectedSubscription{
+            Key:         key,
+            Description: last.Description,
+            CategoryID:  last.CategoryID,
+            Amount:      amount,
+            Period:      period,
+            Charges:     len(list),
+            LastDate:    last.Date,
+            NextDate:    next,
+            AnnualCost:  amount * 365.25 / periodDays,
+        })
+    }
+
+    sort.Slice(detected, func(i, j int) bool { return detected[i].AnnualCost > detected[j].AnnualCost })
+    return detected, nil
+}
+
+// knownSubscriptionKeys возвращает ключи уже заведенных регулярных расходов и скрытых подписок
+func knownSubscriptionKeys(userID uint) (map[string]bool, error) {
+    known := make(map[string]bool)
+
+    recurring, err := GetUserRecurringTransactions(userID)
+    if err != nil {
+        return nil, err
+    }
+    for _, rt := range recurring {
+        if rt.Type == TransactionTypeExpense {
+            known[subscriptionKey(rt.Description)] = true
+        }
+    }
+
+    rows, err := db.DB.Query("SELECT key FROM subscription_dismissals WHERE user_id = $1", userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var key string
+        if err := rows.Scan(&key); err != nil {
+            return nil, err
+        }
+        known[key] = true
+    }
+    return known, rows.Err()
+}
+
+func findSubscription(userID uint, key string, now time.Time) (*DetectedSubscription, error) {
+    detected, err := DetectSubscriptions(userID, now)
+    if err != nil {
+        return nil, err
+    }
+    for i := range detected {
+        if detected[i].Key == key {
+            return &detected[i], nil
+        }
+    }
+    return nil, ErrNotFound
+}
+
+// ConfirmSubscription заводит регулярный расход по найденной подписке.
+// Расписание отсчитывается от последнего списания
+func ConfirmSubscription(userID uint, key string, now time.Time) (*RecurringTransaction, error) {
+    s, err := findSubscription(userID, key, now)
+    if err != nil {
+        return nil, err
+    }
+    return CreateRecurringTransaction(userID, s.CategoryID, s.Amount, TransactionTypeExpense, s.Description, s.Period, s.LastDate)
+}
+
+// DismissSubscription скрывает найденную подписку из списка
+func DismissSubscription(userID uint, key string, now time.Time) error {
+    if _, err := findSubscription(userID, key, now); err != nil {
+        return err
+    }
+
+    _, err := db.DB.Exec(
+        `INSERT INTO subscription_dismissals (user_id, key, created_at) VALUES ($1, $2, NOW())
+         ON CONFLICT (user_id, key) DO NOTHING`,
+        userID, key,
+    )
+    return err
+}
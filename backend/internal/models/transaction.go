@@ -1,11 +1,23 @@
 package models
 
 import (
+	"context"
 	"finance/internal/db"
 	"database/sql"
 	"time"
 )
 
+// Типы транзакций. Категории имеют те же типы, и тип транзакции
+// должен совпадать с типом ее категории
+const (
+	TransactionTypeIncome  = "income"
+	TransactionTypeExpense = "expense"
+)
+
+func ValidTransactionType(transactionType string) bool {
+	return transactionType == TransactionTypeIncome || transactionType == TransactionTypeExpense
+}
+
 type Transaction struct {
 	ID          uint    `json:"id"`
 	UserID      uint    `json:"user_id"`
@@ -16,26 +28,43 @@ type Transaction struct {
 	Date        time.Time `json:"date"`
 }
 
-func CreateTransaction(userID uint, categoryID *uint, amount float64, transactionType, description string) (*Transaction, error) {
-	var id uint
-	var err error
-	
-	if categoryID != nil {
-		err = db.DB.QueryRow(
-			"INSERT INTO transactions (user_id, category_id, amount, type, description, date) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-			userID, categoryID, amount, transactionType, description, time.Now(),
-		).Scan(&id)
-	} else {
-		err = db.DB.QueryRow(
-			"INSERT INTO transactions (user_id, amount, type, description, date) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-			userID, amount, transactionType, description, time.Now(),
-		).Scan(&id)
+func CreateTransaction(ctx context.Context, userID uint, categoryID *uint, amount float64, transactionType, description string) (*Transaction, error) {
+	var transaction *Transaction
+
+	// Транзакция и обновление бюджетов выполняются атомарно
+	err := db.WithTx(ctx, func(tx db.Querier) error {
+		var err error
+		transaction, err = insertTransaction(ctx, tx, userID, categoryID, amount, transactionType, description, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if transactionType == TransactionTypeExpense && categoryID != nil {
+			return AddBudgetSpent(ctx, tx, userID, *categoryID, amount, transaction.Date)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	
+
+	return transaction, nil
+}
+
+func insertTransaction(ctx context.Context, q db.Querier, userID uint, categoryID *uint, amount float64, transactionType, description string, date time.Time) (*Transaction, error) {
+	var id uint
+	err := q.QueryRowContext(ctx,
+		"INSERT INTO transactions (user_id, category_id, amount, type, description, date) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		userID, categoryID, amount, transactionType, description, date,
+	).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := addRollup(ctx, q, userID, categoryID, transactionType, amount, date); err != nil {
+		return nil, err
+	}
+
 	return &Transaction{
 		ID:          id,
 		UserID:      userID,
@@ -43,7 +72,7 @@ func CreateTransaction(userID uint, categoryID *uint, amount float64, transactio
 		Amount:      amount,
 		Type:        transactionType,
 		Description: description,
-		Date:        time.Now(),
+		Date:        date,
 	}, nil
 }
 
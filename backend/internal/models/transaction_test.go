@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"finance/internal/db"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Тесты работают с настоящей базой: параметры берутся из тех же переменных
+// окружения, что и у сервера. Без DB_HOST тесты пропускаются
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST не задан, тест требует PostgreSQL")
+	}
+	if db.DB != nil {
+		return
+	}
+	err := db.InitDB(
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+}
+
+// createTestBudget создает пользователя с категорией расходов и бюджетом на текущий месяц
+func createTestBudget(t *testing.T) (uint, uint, uint) {
+	t.Helper()
+	user, err := CreateUser(fmt.Sprintf("budget-test-%d@example.com", time.Now().UnixNano()), "password", "Test")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, query := range []string{
+			"DELETE FROM daily_rollups WHERE user_id = $1",
+			"DELETE FROM monthly_rollups WHERE user_id = $1",
+			"DELETE FROM transactions WHERE user_id = $1",
+			"DELETE FROM budgets WHERE user_id = $1",
+			"DELETE FROM categories WHERE user_id = $1",
+			"DELETE FROM users WHERE id = $1",
+		} {
+			if _, err := db.DB.Exec(query, user.ID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+
+	category, err := CreateCategory(user.ID, "Food", TransactionTypeExpense, nil, "", "", "")
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+
+	start, end := PeriodBounds(PeriodMonthly, 1, time.Now())
+	budget, err := CreateBudget(context.Background(), user.ID, []uint{category.ID}, 1000, start, end)
+	if err != nil {
+		t.Fatalf("CreateBudget: %v", err)
+	}
+	return user.ID, category.ID, budget.ID
+}
+
+func budgetSpent(t *testing.T, budgetID uint) float64 {
+	t.Helper()
+	var spent float64
+	if err := db.DB.QueryRow("SELECT spent FROM budgets WHERE id = $1", budgetID).Scan(&spent); err != nil {
+		t.Fatalf("budget spent: %v", err)
+	}
+	return spent
+}
+
+func TestCreateTransactionConcurrentBudgetSpent(t *testing.T) {
+	setupTestDB(t)
+	userID, categoryID, budgetID := createTestBudget(t)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 1; i <= workers; i++ {
+		wg.Add(1)
+		go func(amount float64) {
+			defer wg.Done()
+			_, err := CreateTransaction(context.Background(), userID, &categoryID, amount, TransactionTypeExpense, "concurrent")
+			errs <- err
+		}(float64(i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("CreateTransaction: %v", err)
+		}
+	}
+
+	// 1 + 2 + ... + workers
+	want := float64(workers * (workers + 1) / 2)
+	if spent := budgetSpent(t, budgetID); spent != want {
+		t.Errorf("spent = %v, want %v", spent, want)
+	}
+}
+
+func TestCreateTransactionRollbackOnBudgetError(t *testing.T) {
+	setupTestDB(t)
+	userID, categoryID, budgetID := createTestBudget(t)
+
+	// Обновление именно этого бюджета падает, как если бы сломался AddBudgetSpent
+	trigger := fmt.Sprintf("budget_test_fail_%d", budgetID)
+	setup := []string{
+		`CREATE FUNCTION ` + trigger + `() RETURNS trigger AS $$
+         BEGIN
+             RAISE EXCEPTION 'budget update failed';
+         END;
+         $$ LANGUAGE plpgsql`,
+		fmt.Sprintf(`CREATE TRIGGER %s BEFORE UPDATE ON budgets
+         FOR EACH ROW WHEN (OLD.id = %d) EXECUTE FUNCTION %s()`, trigger, budgetID, trigger),
+	}
+	for _, query := range setup {
+		if _, err := db.DB.Exec(query); err != nil {
+			t.Fatalf("setup trigger: %v", err)
+		}
+	}
+	defer func() {
+		db.DB.Exec("DROP TRIGGER IF EXISTS " + trigger + " ON budgets")
+		db.DB.Exec("DROP FUNCTION IF EXISTS " + trigger + "()")
+	}()
+
+	if _, err := CreateTransaction(context.Background(), userID, &categoryID, 50, TransactionTypeExpense, "rollback"); err == nil {
+		t.Fatal("CreateTransaction succeeded, want budget update error")
+	}
+
+	var count int
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM transactions WHERE user_id = $1", userID).Scan(&count); err != nil {
+		t.Fatalf("count transactions: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("transactions saved = %d, want 0", count)
+	}
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM daily_rollups WHERE user_id = $1", userID).Scan(&count); err != nil {
+		t.Fatalf("count rollups: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("rollups saved = %d, want 0", count)
+	}
+	if spent := budgetSpent(t, budgetID); spent != 0 {
+		t.Errorf("spent = %v, want 0", spent)
+	}
+}
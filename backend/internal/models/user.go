@@ -1,8 +1,10 @@
 package models
 
 import (
+    "context"
     "finance/internal/db"
     "golang.org/x/crypto/bcrypt"
+    "time"
 )
 
 type User struct {
@@ -12,6 +14,13 @@ type User struct {
     Name     string `json:"name"`
 }
 
+// UserSettings - настройки, от которых зависят периоды статистики и бюджетов.
+// MonthStartDay - день начала финансового месяца (день зарплаты), 1-28
+type UserSettings struct {
+    Timezone      string `json:"timezone"`
+    MonthStartDay int    `json:"month_start_day"`
+}
+
 func CreateUser(email, password, name string) (*User, error) {
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
     if err != nil {
@@ -49,6 +58,85 @@ func GetUserByEmail(email string) (*User, error) {
     return &user, nil
 }
 
+func GetUserBudgetMode(userID uint) (string, error) {
+    var mode string
+    err := db.DB.QueryRow("SELECT budget_mode FROM users WHERE id = $1", userID).Scan(&mode)
+    if err != nil {
+        return "", err
+    }
+    return mode, nil
+}
+
+func SetUserBudgetMode(userID uint, mode string) error {
+    _, err := db.DB.Exec("UPDATE users SET budget_mode = $1 WHERE id = $2", mode, userID)
+    return err
+}
+
+// ValidTimezone проверяет имя часового пояса IANA (Europe/Moscow)
+func ValidTimezone(timezone string) bool {
+    if timezone == "" || timezone == "Local" {
+        return false
+    }
+    _, err := time.LoadLocation(timezone)
+    return err == nil
+}
+
+func GetUserTimezone(userID uint) (string, error) {
+    var timezone string
+    err := db.DB.QueryRow("SELECT timezone FROM users WHERE id = $1", userID).Scan(&timezone)
+    if err != nil {
+        return "", err
+    }
+    return timezone, nil
+}
+
+// UserLocation возвращает часовой пояс пользователя, в котором считается статистика
+func UserLocation(userID uint) (*time.Location, error) {
+    timezone, err := GetUserTimezone(userID)
+    if err != nil {
+        return nil, err
+    }
+    return (&UserSettings{Timezone: timezone}).Location(), nil
+}
+
+func (s *UserSettings) Location() *time.Location {
+    loc, err := time.LoadLocation(s.Timezone)
+    if err != nil {
+        return time.UTC
+    }
+    return loc
+}
+
+func GetUserSettings(userID uint) (*UserSettings, error) {
+    var s UserSettings
+    err := db.DB.QueryRow(
+        "SELECT timezone, month_start_day FROM users WHERE id = $1",
+        userID,
+    ).Scan(&s.Timezone, &s.MonthStartDay)
+    if err != nil {
+        return nil, err
+    }
+    return &s, nil
+}
+
+// SetUserMonthStart задает день начала финансового месяца. Уже созданные бюджеты
+// сохраняют свои даты, новые периоды считаются от нового дня
+func SetUserMonthStart(userID uint, day int) error {
+    _, err := db.DB.Exec("UPDATE users SET month_start_day = $1 WHERE id = $2", day, userID)
+    return err
+}
+
+// SetUserTimezone меняет часовой пояс и пересобирает агрегаты пользователя по дням нового пояса
+func SetUserTimezone(ctx context.Context, userID uint, timezone string) error {
+    return db.WithTx(ctx, func(tx db.Querier) error {
+        _, err := tx.ExecContext(ctx, "UPDATE users SET timezone = $1 WHERE id = $2", timezone, userID)
+        if err != nil {
+            return err
+        }
+        return rebuildRollups(ctx, tx, &userID)
+    })
+}
+
 func (u *User) CheckPassword(password string) bool {
     err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
     return err == nil